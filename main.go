@@ -1,16 +1,21 @@
 package main
 
 import (
-	"artistdb-go/src/artist"
+	"artistdb-go/src/cli"
+	"artistdb-go/src/migrations"
+	"artistdb-go/src/reload"
 	"artistdb-go/src/routes"
 	"artistdb-go/src/utils"
+	"artistdb-go/src/watcher"
+	"context"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/lmittmann/tint"
-	"github.com/rjeczalik/notify"
 )
 
 func init() {
@@ -23,52 +28,77 @@ func init() {
 }
 
 func main() {
-	appState := utils.NewAppState()
-
-	// read file & parse for 1st time
-	func() {
-		rawBytes, err := os.ReadFile(appState.GetInFile())
-		if err != nil {
-			slog.Error(err.Error())
-			os.Exit(1)
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "fmt":
+			os.Exit(cli.Format(os.Args[2:]))
+		case "validate":
+			os.Exit(cli.Validate(os.Args[2:]))
+		case "lsp":
+			os.Exit(cli.LSP(os.Args[2:]))
+		case "codes":
+			os.Exit(cli.Codes(os.Args[2:]))
+		default:
+			slog.Error("unknown command", "command", os.Args[1])
+			os.Exit(2)
 		}
-		artistCount, err2 := artist.ParseToNewDB(appState, string(rawBytes))
-		if err2 != nil {
-			slog.Error(err2.Message, err2.Props...)
-			os.Exit(1)
-		}
-		slog.Info("parsed artists successfully", "count", artistCount)
-	}()
+	}
+	switch os.Getenv("FORMAT_AND_EXIT") {
+	case "true":
+		os.Exit(cli.Format(nil))
+	case "check":
+		os.Exit(cli.Format([]string{"-check"}))
+	}
 
-	// watch artists.txt for changes and re-parse
-	eventInfoCh := make(chan notify.EventInfo, 1)
-	if err := notify.Watch(appState.GetInFile(), eventInfoCh, notify.InCloseWrite); err != nil {
+	appState := utils.NewAppState()
+	appState.ReservedSegments = routes.ReservedSegments()
+	if err := migrations.Migrate(context.Background(), appState.DB); err != nil {
 		slog.Error(err.Error())
+		os.Exit(1)
 	}
-	defer notify.Stop(eventInfoCh)
-	go func() {
-		for {
-			switch event := <-eventInfoCh; event.Event() {
-			case notify.InCloseWrite:
-				rawBytes, err := os.ReadFile(appState.GetInFile())
-				if err != nil {
-					slog.Error(err.Error())
-				}
-				artistCount, err2 := artist.ParseToNewDB(appState, string(rawBytes))
-				if err2 != nil {
-					slog.Error(err2.Message, err2.Props...)
 
+	// read file & parse for 1st time
+	reloader := reload.NewReloader(appState)
+	if status := reloader.ReloadNow(); status.Failed {
+		reloader.Export()
+	}
+	go reloader.Run(context.Background())
+
+	// watch artists.txt, or every artists file in the IN_FILE directory, for
+	// changes and re-parse
+	go watcher.NewWatcher(
+		appState.GetInFile(),
+		appState.GetWatchMode(),
+		appState.GetWatchPollInterval(),
+		reloader.Trigger,
+	).Run(context.Background())
+
+	// watch SOCIALS_FILE too, the artists are re-parsed with the new socials
+	if socialsFile := appState.GetSocialsFile(); socialsFile != "" {
+		go watcher.NewWatcher(
+			socialsFile,
+			appState.GetWatchMode(),
+			appState.GetWatchPollInterval(),
+			func(reason string) {
+				if err := appState.SupportedSocials.Load(socialsFile); err != nil {
+					slog.Error("can't reload the socials file, keeping the previous socials", "err", err)
+					return
 				}
-				slog.Info("parsed artists successfully", "count", artistCount)
-			}
+				reloader.Trigger(reason)
+			},
+		).Run(context.Background())
+	}
+
+	// kill -HUP also re-parses
+	sighupCh := make(chan os.Signal, 1)
+	signal.Notify(sighupCh, syscall.SIGHUP)
+	go func() {
+		for range sighupCh {
+			reloader.Trigger("SIGHUP")
 		}
 	}()
 
-	http.HandleFunc("GET /", routes.GetIndex)
-	http.HandleFunc("GET /{username}", routes.GetArtist(appState))
-	http.HandleFunc("GET /style.css", routes.StyleCSS)
-	http.HandleFunc("GET /avatar/{fileName}", routes.GetAvatar(appState))
-	http.HandleFunc("GET /font/{fontName}", routes.GetFont)
+	routes.Register(appState, reloader)
 
 	slog.Info("listening on port " + appState.GetPort())
 	if err := http.ListenAndServe(":"+appState.GetPort(), nil); err != nil {
@@ -3,35 +3,19 @@ package artist
 import (
 	"artistdb-go/src/utils"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"log/slog"
-	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/uptrace/bun"
 )
 
-var (
-	WRONG_AVATAR_FORMAT = "avatar must have a format of username@socialcode, leave empty or use underscore to auto infer"
-	DOUBLE_NEWLINE_RGX  = regexp.MustCompile(`\n{2,}`)
-)
-
-type SlogErr struct {
-	Message string
-	Props   []any
-}
-
-func NewSlogErr(message string, props ...any) *SlogErr {
-	if props == nil {
-		props = make([]any, 0)
-	}
-	return &SlogErr{
-		Message: message,
-		Props:   props,
-	}
-}
+var WRONG_AVATAR_FORMAT = "avatar must have a format of username@socialcode, leave empty or use underscore to auto infer"
 
 type ArtistDB struct {
 	bun.BaseModel `bun:"table:artist"`
@@ -39,9 +23,42 @@ type ArtistDB struct {
 	ID          string `bun:"id,pk,unique,notnull"`
 	DisplayName string `bun:"display_name"`
 	Avatar      string `bun:"avatar"`
-	Socials     string `bun:"socials"`
+	// hash of everything in the artist, to find changed artists
+	Hash string `bun:"hash"`
 
-	Aliases []string `bun:"-"`
+	Socials []SocialDB `bun:"rel:has-many,join:id=artist_id"`
+	Aliases []string   `bun:"-"`
+}
+
+// ComputeHash hashes the content of the artist, including its socials and
+// aliases
+func (artist *ArtistDB) ComputeHash() string {
+	aliases := append([]string{}, artist.Aliases...)
+	sort.Strings(aliases)
+	values := []string{artist.ID, artist.DisplayName, artist.Avatar}
+	for _, social := range artist.Socials {
+		values = append(values,
+			social.SocialCode, social.Handle, social.Link, social.Description,
+			strconv.FormatBool(social.IsSpecial))
+	}
+	values = append(values, aliases...)
+
+	hash := sha256.New()
+	for _, value := range values {
+		hash.Write([]byte(value))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// AliasModels returns the alias rows of the artist, including the username
+// itself
+func (artist *ArtistDB) AliasModels() []AliasDB {
+	aliases := make([]AliasDB, 0, len(artist.Aliases)+1)
+	for _, alias := range artist.Aliases {
+		aliases = append(aliases, AliasDB{ID: artist.ID, Alias: alias})
+	}
+	return append(aliases, AliasDB{ID: artist.ID, Alias: artist.ID})
 }
 
 type AliasDB struct {
@@ -51,228 +68,569 @@ type AliasDB struct {
 	ID    string `bun:"artist_id,notnull"`
 }
 
-// ParseToNewDB parses the artist string and returns a map of artists. Key: pointer
-// to username, Value: Artist struct.
-func ParseToNewDB(appState *utils.AppState, artistString string) (int, *SlogErr) {
-	// reset database & temp vars
-	startTimer := time.Now()
-	if err := appState.DB.
-		ResetModel(
-			context.Background(),
-			(*ArtistDB)(nil),
-			(*AliasDB)(nil)); err != nil {
-		return 0, NewSlogErr("ParseToNewDB", "err", err)
-	}
-	appState.UsernameSet = make(map[string]struct{})
-	appState.AliasSet = make(map[string]struct{})
-	slog.Info("reset DB, clear temp vars", "time", time.Since(startTimer))
-
-	// split, rm empty lines, sort
-	artistRawStrings := DOUBLE_NEWLINE_RGX.Split(artistString, -1)
-	sort.Slice(artistRawStrings, func(i, j int) bool {
-		return artistRawStrings[i] < artistRawStrings[j]
-	})
+const (
+	// any error fails the whole parse
+	PARSE_STRICT = "strict"
+	// artists and socials with errors are skipped, the rest is loaded
+	PARSE_LENIENT = "lenient"
+)
+
+// ParseResult is what ParseToNewDB and ParseSources found
+type ParseResult struct {
+	ArtistCount int
+	// aliases of the parsed artists, not counting their usernames
+	AliasCount int
+	// see HashSources
+	SourceHash  string
+	Diagnostics Diagnostics
+	// artists and socials left out in lenient mode
+	Skipped []Skipped
+	// only set by ParseToNewDB
+	Changes ChangeSet
+}
+
+// Skipped is an artist or a social line left out in lenient mode because of
+// its errors, there's one per artist or social line however many errors it has
+type Skipped struct {
+	// code of the first error, the others are in the diagnostics
+	Code Code `json:"code"`
+	// artist or social
+	Kind   string `json:"kind"`
+	Artist string `json:"artist"`
+	// where the username or the social line starts
+	Position Position `json:"position"`
+	Reason   string   `json:"reason"`
+}
+
+// ParseToNewDB parses the artists files and updates the artists and aliases in
+// the database. Usernames and aliases must be unique across all files. Every
+// problem found is returned as a diagnostic, sorted by position. In strict
+// mode the database is left untouched if any of them is an error, in lenient
+// mode the artists and socials with errors are skipped.
+//
+// Only the artists whose hash changed are written, in one transaction, so
+// readers either see the old or the new data and a failed parse keeps the last
+// good data online.
+func ParseToNewDB(appState *utils.AppState, sources []Source) (ParseResult, error) {
+	ctx := context.Background()
 
 	// parse artists into DB models
+	startTimer := time.Now()
+	artistsToDB, result := ParseSources(appState, sources)
+	if err := result.Diagnostics.Err(); err != nil {
+		return result, err
+	}
+	slog.Info("artists parsed to DB models", "time", time.Since(startTimer))
+
+	// write the changed artists
 	startTimer = time.Now()
+	changes, err := Sync(ctx, appState.DB, artistsToDB)
+	if err != nil {
+		return result, fmt.Errorf("ParseToNewDB: can't sync database: %w", err)
+	}
+	changes.Log()
+	slog.Info("database synced", "time", time.Since(startTimer))
+
+	result.ArtistCount = len(artistsToDB)
+	result.Changes = changes
+	return result, nil
+}
+
+// ParseSources parses the artists files into DB models sorted by username,
+// without touching the database. The models are only usable if none of the
+// diagnostics is an error; in lenient mode what the errors affect is skipped
+// and they're flagged as such, see Skipped.
+func ParseSources(appState *utils.AppState, sources []Source) ([]ArtistDB, ParseResult) {
+	lenient := appState.GetParseMode() == PARSE_LENIENT
+	state := NewParseState()
+	result := ParseResult{
+		Diagnostics: make(Diagnostics, 0),
+		Skipped:     make([]Skipped, 0),
+	}
 	artistsToDB := make([]ArtistDB, 0)
-	for _, artistString := range artistRawStrings {
-		artist := Artist{}
-		artistModel, err := artist.Unmarshal(appState, artistString)
-		if err != nil {
-			return 0, NewSlogErr("ParseToNewDB", "err", err)
+	for _, source := range sources {
+		for _, block := range SplitBlocks(source.Content) {
+			artist := Artist{}
+			artistModel, artistDiags := artist.Unmarshal(appState, state, block.Raw, block.StartLine)
+			artistDiags.InFile(source.Name)
+			artistDiags.ForArtist(artist.Username)
+			skipArtist := artistDiags.hasArtistErrors()
+			if lenient && skipArtist {
+				state.rollback()
+			}
+			if lenient {
+				result.Skipped = append(result.Skipped, artist.skip(artistDiags, skipArtist)...)
+			}
+			result.Diagnostics = append(result.Diagnostics, artistDiags...)
+			if skipArtist || artistModel.ID == "" {
+				// errors or a block made only of comments
+				continue
+			}
+			artistsToDB = append(artistsToDB, artistModel)
 		}
-		artistsToDB = append(artistsToDB, artistModel)
 	}
-	slog.Info("artists parsed to DB models", "time", time.Since(startTimer))
+	result.Diagnostics.Sort()
+	sort.SliceStable(result.Skipped, func(i, j int) bool {
+		return result.Skipped[i].Position.before(result.Skipped[j].Position)
+	})
+	sort.Slice(artistsToDB, func(i, j int) bool {
+		return artistsToDB[i].ID < artistsToDB[j].ID
+	})
+	result.ArtistCount = len(artistsToDB)
+	for _, artistModel := range artistsToDB {
+		result.AliasCount += len(artistModel.Aliases)
+	}
+	result.SourceHash = HashSources(sources)
+	return artistsToDB, result
+}
 
-	// insert into DB
-	startTimer = time.Now()
-	if _, err := appState.DB.NewInsert().
-		Model(&artistsToDB).
-		Exec(context.Background()); err != nil {
-		return 0, NewSlogErr("ParseToNewDB", "err", err)
-	}
-	slog.Info("artists inserted into DB", "time", time.Since(startTimer))
-
-	// prepare alias into DB models
-	aliasesToDB := make([]AliasDB, 0)
-	for _, artist := range artistsToDB {
-		for _, alias := range artist.Aliases {
-			aliasesToDB = append(aliasesToDB, AliasDB{
-				ID:    artist.ID,
-				Alias: alias,
-			})
+// skip flags the errors of the artist as skipped in lenient mode, and returns
+// what they leave out: the whole artist, or each social line with errors
+func (artist *Artist) skip(diags Diagnostics, skipArtist bool) []Skipped {
+	diags.Sort()
+	skipped := make([]Skipped, 0)
+	socialLines := make(map[int]struct{})
+	for i, diag := range diags {
+		if diag.Severity != SeverityError {
+			continue
 		}
-		aliasesToDB = append(aliasesToDB, AliasDB{
-			ID:    artist.ID,
-			Alias: artist.ID,
-		})
+		diags[i].Skipped = true
+		entry := Skipped{
+			Code:   diag.Code,
+			Kind:   "social",
+			Artist: artist.Username,
+			Reason: diag.Message,
+		}
+		switch {
+		case skipArtist && len(skipped) > 0:
+			continue
+		case skipArtist:
+			entry.Kind = "artist"
+			entry.Position = artist.usernamePos
+		default:
+			if _, ok := socialLines[diag.Position.Line]; ok {
+				continue
+			}
+			socialLines[diag.Position.Line] = struct{}{}
+			entry.Position = diag.Position
+			for _, social := range artist.Socials {
+				if social.Position.Line == diag.Position.Line {
+					entry.Position = social.Position
+				}
+			}
+		}
+		entry.Position.File = diag.Position.File
+		skipped = append(skipped, entry)
 	}
+	return skipped
+}
 
-	// insert
-	startTimer = time.Now()
-	if _, err := appState.DB.NewInsert().
-		Model(&aliasesToDB).
-		Exec(context.Background()); err != nil {
-		return 0, NewSlogErr("ParseToNewDB", "err", err)
+// ParseState holds the usernames and aliases seen so far in one parse, to find
+// duplicates. Each parse gets its own, so parses never share state.
+type ParseState struct {
+	usernames map[string]struct{}
+	aliases   map[string]struct{}
+	// skeleton of every username and alias, see utils.Skeleton, to the first
+	// name with that skeleton
+	skeletons map[string]string
+	// undoes what the current artist added, see rollback
+	journal []func()
+}
+
+// begin starts recording the names an artist adds to the state
+func (state *ParseState) begin() {
+	state.journal = state.journal[:0]
+}
+
+// rollback removes the names added since begin, for an artist that's skipped
+// in lenient mode, so it doesn't collide with the artists after it
+func (state *ParseState) rollback() {
+	for _, undo := range state.journal {
+		undo()
 	}
-	slog.Info("aliases inserted into DB", "time", time.Since(startTimer))
+	state.journal = state.journal[:0]
+}
+
+func (state *ParseState) addUsername(name string) {
+	if _, ok := state.usernames[name]; ok {
+		return
+	}
+	state.usernames[name] = struct{}{}
+	state.journal = append(state.journal, func() { delete(state.usernames, name) })
+}
+
+func (state *ParseState) addAlias(name string) {
+	if _, ok := state.aliases[name]; ok {
+		return
+	}
+	state.aliases[name] = struct{}{}
+	state.journal = append(state.journal, func() { delete(state.aliases, name) })
+}
 
-	appState.UsernameSet = make(map[string]struct{})
-	appState.AliasSet = make(map[string]struct{})
-	return len(artistsToDB), nil
+func NewParseState() *ParseState {
+	return &ParseState{
+		usernames: make(map[string]struct{}),
+		aliases:   make(map[string]struct{}),
+		skeletons: make(map[string]string),
+	}
 }
 
+// Artist is one artist block as written in the artists file
 type Artist struct {
 	Original string
+
+	Username string
+	// empty when using the _ placeholder
+	DisplayName string
+	// empty when omitted, _ to infer from the socials
+	Avatar  string
+	Aliases []string
+	Socials []Social
+
+	// whole-line comments above the header
+	Comments []string
+	// trailing comment of the header line
+	HeaderComment string
+	// whole-line comments after the last social
+	TrailingComments []string
+
+	usernamePos Position
+	avatarPos   Position
+	aliasPos    []Position
 }
 
-// Unmarshal parses the artists.txt and inserts into database
-func (artist *Artist) Unmarshal(appState *utils.AppState, rawString string) (ArtistDB, *SlogErr) {
+// Parse reads the block into the artist without resolving anything against the
+// supported socials or the other artists, so it can be used to reformat the
+// file. Social lines that can't be parsed are kept with only Raw set, and the
+// error is scoped to the social.
+//
+// Comments start with #, either on their own line or after a space at the end
+// of a line. A whole-line comment belongs to the social below it, or to the
+// header if it's above it. A trailing comment right after a display name or a
+// description is warned about, since the # could be part of the text.
+func (artist *Artist) Parse(rawString string, startLine int) Diagnostics {
 	artist.Original = rawString
+	diags := make(Diagnostics, 0)
 
-	// split, rm empty lines, check length
-	lines := make([]string, 0)
-	for _, line := range strings.Split(rawString, "\n") {
-		if line != "" {
-			lines = append(lines, line)
+	// split, rm empty lines & comments, check length
+	type numberedLine struct {
+		text     string
+		comment  string
+		comments []string
+		pos      Position
+	}
+	lines := make([]numberedLine, 0)
+	comments := make([]string, 0)
+	for i, line := range strings.Split(rawString, "\n") {
+		line, comment := splitComment(strings.TrimRight(line, "\r"))
+		switch {
+		case strings.TrimSpace(line) != "":
+			lines = append(lines, numberedLine{line, comment, comments, Position{Line: startLine + i, Column: 1}})
+			comments = make([]string, 0)
+		case comment != "":
+			comments = append(comments, comment)
 		}
 	}
-	if len(lines) < 2 {
-		return ArtistDB{}, NewSlogErr("Artist.Unmarshal", "artist", rawString)
+	artist.TrailingComments = comments
+	if len(lines) == 0 {
+		return diags
 	}
+	artist.Comments = lines[0].comments
+	artist.HeaderComment = lines[0].comment
 
 	// parse info
-	infoData := strings.Split(lines[0], ",")
-	artist.Original = lines[0]
-
-	// username, display name & check duplicate username
-	var username, displayName string
-	username = strings.ToLower(infoData[0])
-	if (len(infoData) > 1) && (infoData[1] != "") && (infoData[1] != "_") {
-		displayName = infoData[1]
-	} else {
-		displayName = username
+	infoData := splitFields(lines[0].text, ',')
+	at := func(f field) Position {
+		return Position{Line: lines[0].pos.Line, Column: f.Column}
 	}
-	if _, ok := appState.UsernameSet[username]; ok {
-		return ArtistDB{}, NewSlogErr(
-			"Artist.Unmarshal: duplicate username found in username pool",
-			"artist", username)
+	for _, infoField := range infoData {
+		if infoField.Unclosed {
+			diags.Add(NewDiagnostic(CODE_UNCLOSED_QUOTE, at(infoField), infoField.Value))
+		}
+	}
+	artist.Username = utils.NormalizeName(infoData[0].Value)
+	artist.usernamePos = at(infoData[0])
+	if artist.Username == "" {
+		diags.Add(NewDiagnostic(CODE_EMPTY_USERNAME, artist.usernamePos, ""))
+	} else if diag := checkURLSafe(artist.Username, artist.usernamePos); diag != nil {
+		diags.Add(*diag)
 	}
-	if _, ok := appState.AliasSet[username]; ok {
-		return ArtistDB{}, NewSlogErr(
-			"Artist.Unmarshal: username found in alias pool",
-			"artist", username)
+	if len(infoData) > 1 && (infoData[1].Value != "_" || infoData[1].Quoted) {
+		artist.DisplayName = infoData[1].Value
+	}
+	if f, ok := commentAfterText(lines[0].text, lines[0].comment, 1); ok {
+		diags.Add(NewDiagnostic(CODE_COMMENT_AFTER_TEXT, at(f), lines[0].comment))
+	}
+	if len(infoData) > 2 {
+		artist.Avatar = infoData[2].Value
+		if artist.Avatar == "" {
+			artist.Avatar = "_"
+		}
+		artist.avatarPos = at(infoData[2])
 	}
-	appState.UsernameSet[username] = struct{}{}
 
-	// alias & check duplicate
-	alias := func() []string {
+	// alias, deduplicated
+	artist.Aliases = make([]string, 0)
+	artist.aliasPos = make([]Position, 0)
+	if len(infoData) > 3 {
 		aliasMap := make(map[string]struct{}, 0)
-		if len(infoData) > 3 {
-			for i := 3; i < len(infoData); i++ {
-				if infoData[i] != "" {
-					aliasMap[strings.ToLower(infoData[i])] = struct{}{}
-				}
+		for _, aliasField := range infoData[3:] {
+			alias := utils.NormalizeName(aliasField.Value)
+			if alias == "" || alias == "_" {
+				continue
+			}
+			if _, ok := aliasMap[alias]; ok {
+				diags.Add(NewDiagnostic(CODE_REPEATED_ALIAS, at(aliasField), alias))
+				continue
+			}
+			if diag := checkURLSafe(alias, at(aliasField)); diag != nil {
+				diags.Add(*diag)
 			}
+			aliasMap[alias] = struct{}{}
+			artist.Aliases = append(artist.Aliases, alias)
+			artist.aliasPos = append(artist.aliasPos, at(aliasField))
+		}
+	}
+
+	if len(lines) < 2 {
+		diags.Add(NewDiagnostic(CODE_NO_SOCIALS, lines[0].pos, ""))
+	}
+
+	// socials
+	artist.Socials = make([]Social, 0)
+	for _, line := range lines[1:] {
+		social := Social{}
+		if diag := social.Parse(artist.Username, line.text, line.pos); diag != nil {
+			diag.socialScope = true
+			diags = append(diags, *diag)
+		}
+		social.Comments = line.comments
+		social.Comment = line.comment
+		if f, ok := commentAfterText(line.text, line.comment, 1); ok {
+			diags.Add(NewDiagnostic(CODE_COMMENT_AFTER_TEXT, Position{Line: line.pos.Line, Column: f.Column}, line.comment))
+		}
+		artist.Socials = append(artist.Socials, social)
+	}
+
+	diags.ForArtist(artist.Username)
+	return diags
+}
+
+// UsernamePosition is where the username starts in the artists file
+func (artist *Artist) UsernamePosition() Position {
+	return artist.usernamePos
+}
+
+// AvatarPosition is where the avatar starts in the artists file, the zero
+// Position if it's omitted
+func (artist *Artist) AvatarPosition() Position {
+	return artist.avatarPos
+}
+
+// AliasPositions is where each of Aliases starts in the artists file
+func (artist *Artist) AliasPositions() []Position {
+	return artist.aliasPos
+}
+
+// Unmarshal parses one artist block. startLine is the line the block starts at
+// in the artists file, used to position the diagnostics; state holds the
+// usernames and aliases seen so far in the same parse. The returned model is
+// only usable if none of the diagnostics is an error.
+func (artist *Artist) Unmarshal(appState *utils.AppState, state *ParseState, rawString string, startLine int) (ArtistDB, Diagnostics) {
+	// a header error doesn't stop the checks below, so every problem of the
+	// block is reported at once; only the model is withheld
+	state.begin()
+	diags := artist.Parse(rawString, startLine)
+	username := artist.Username
+	displayName := artist.DisplayName
+	if displayName == "" {
+		displayName = username
+	}
+
+	// check duplicate username
+	if username != "" {
+		if _, ok := state.usernames[username]; ok {
+			diags.Add(NewDiagnostic(CODE_DUPLICATE_USERNAME, artist.usernamePos, username))
 		}
-		aliasSlice := make([]string, 0)
-		for alias := range aliasMap {
-			aliasSlice = append(aliasSlice, alias)
+		if _, ok := state.aliases[username]; ok {
+			diags.Add(NewDiagnostic(CODE_USERNAME_IS_ALIAS, artist.usernamePos, username))
 		}
-		return aliasSlice
-	}()
-	for _, alias := range alias {
-		if _, ok := appState.UsernameSet[alias]; ok {
-			return ArtistDB{}, NewSlogErr(
-				"Artist.Unmarshal: alias found in username pool",
-				"artist", username, "alias", alias)
+		state.addUsername(username)
+		if diag := checkReserved(appState.ReservedSegments, username, artist.usernamePos); diag != nil {
+			diags.Add(*diag)
 		}
+		if diag := state.checkConfusable(username, artist.usernamePos); diag != nil {
+			diags.Add(*diag)
+		}
+	}
 
-		if _, ok := appState.AliasSet[alias]; ok {
-			return ArtistDB{}, NewSlogErr(
-				"Artist.Unmarshal: duplicate alias found in alias pool",
-				"artist", username, "alias", alias)
+	// check duplicate alias
+	for i, alias := range artist.Aliases {
+		if diag := checkReserved(appState.ReservedSegments, alias, artist.aliasPos[i]); diag != nil {
+			diags.Add(*diag)
+			continue
+		}
+		if _, ok := state.usernames[alias]; ok {
+			diags.Add(NewDiagnostic(CODE_ALIAS_IS_USERNAME, artist.aliasPos[i], alias))
+			continue
+		}
+		if _, ok := state.aliases[alias]; ok {
+			diags.Add(NewDiagnostic(CODE_DUPLICATE_ALIAS, artist.aliasPos[i], alias))
+			continue
+		}
+		state.addAlias(alias)
+		if diag := state.checkConfusable(alias, artist.aliasPos[i]); diag != nil {
+			diags.Add(*diag)
 		}
-		appState.AliasSet[alias] = struct{}{}
 	}
 
 	// socials
 	socials := make([]Social, 0)
-next_social:
-	for _, social := range lines[1:] {
-		artistSocial := Social{}
-		if err := artistSocial.Unmarshal(appState, username, social); err != nil {
-			slog.Error((*err).Message, (*err).Props...)
-			continue next_social
+	for _, social := range artist.Socials {
+		if social.Link == "" && social.SocialCode == "" {
+			// already reported by Parse
+			continue
 		}
-		socials = append(socials, artistSocial)
+		if diag := checkDeprecatedCode(appState.SupportedSocials, social.SocialCode, social.codePos); diag != nil {
+			diag.Artist = username
+			diags.Add(*diag)
+		}
+		if diag := social.Resolve(appState, username); diag != nil {
+			diag.socialScope = true
+			diags = append(diags, *diag)
+			continue
+		}
+		if diag := social.checkProfileLink(appState.SupportedSocials, username); diag != nil {
+			diags.Add(*diag)
+		}
+		socials = append(socials, social)
 	}
 
 	// avatar
 	var avatar string
-	if len(infoData) > 2 {
-		autoInfer := infoData[2] == "_" || infoData[2] == ""
-		usingAtSocial := strings.Contains(infoData[2], "@")
-		usingAbsPath := strings.HasPrefix(infoData[2], "/")
+	if artist.Avatar != "" {
+		autoInfer := artist.Avatar == "_"
+		usingAtSocial := strings.Contains(artist.Avatar, "@")
+		usingAbsPath := strings.HasPrefix(artist.Avatar, "/")
 
 		switch {
 		case usingAtSocial:
-			components := strings.Split(infoData[2], "@")
-			if len(components) != 2 {
-				return ArtistDB{}, NewSlogErr(
-					"Artist.Unmarshal: "+WRONG_AVATAR_FORMAT,
-					"artist", username, "avatar", infoData[2])
-			}
+			// the handle of fediverse socials has an @ too
+			at := strings.LastIndexByte(artist.Avatar, '@')
+			components := []string{artist.Avatar[:at], artist.Avatar[at+1:]}
 
-			result, err := appState.SupportedSocials.
-				ToUnavatarLink(components[0], components[1])
+			unknownSocial := NewDiagnostic(CODE_UNKNOWN_AVATAR_SOCIAL, artist.avatarPos, components[1]).
+				Suggest(utils.Suggest(components[1], appState.SupportedSocials.AvatarCodes()))
+			provider, ok := appState.SupportedSocials.Provider(components[1])
+			if !ok {
+				diags.Add(unknownSocial)
+				break
+			}
+			if diag := checkDeprecatedCode(appState.SupportedSocials, components[1], artist.avatarPos); diag != nil {
+				diags.Add(*diag)
+			}
+			handle := provider.NormalizeHandle(components[0])
+			if err := provider.ValidateHandle(handle); err != nil {
+				diag := NewDiagnostic(CODE_INVALID_HANDLE, artist.avatarPos, components[0])
+				diag.Message += ", " + err.Error()
+				diags.Add(diag)
+				break
+			}
+			result, err := provider.AvatarURL(handle)
 			if err != nil {
-				return ArtistDB{}, NewSlogErr(
-					"Artist.Unmarshal: "+err.Error(),
-					"artist", username, "social", components[1])
+				diags.Add(unknownSocial)
+				break
 			}
 			avatar = result
 		case usingAbsPath:
-			avatar = fmt.Sprintf("/avatar%s", infoData[2])
+			avatar = fmt.Sprintf("/avatar%s", artist.Avatar)
 		case autoInfer:
-			for _, social := range socials {
-				result, err := appState.SupportedSocials.
-					ToUnavatarLink(social.Username, social.SocialCode)
-				if err != nil {
-					continue
-				}
-				avatar = result
-				break
-			}
+			avatar = inferAvatar(appState, socials)
 			if avatar == "" {
-				return ArtistDB{}, NewSlogErr(
-					"Artist.Unmarshal: could not infer avatar from socials",
-					"artist", username, "socials", socials)
+				diags.Add(NewDiagnostic(CODE_CANT_INFER_AVATAR, artist.avatarPos, artist.Avatar))
 			}
 		default:
-			return ArtistDB{}, NewSlogErr(
-				"Artist.Unmarshal: "+WRONG_AVATAR_FORMAT,
-				"artist", username, "avatar", infoData[2])
+			diags.Add(NewDiagnostic(CODE_WRONG_AVATAR_FORMAT, artist.avatarPos, artist.Avatar))
 		}
 	}
 
-	socialsMarshaled := make([]string, 0)
+	diags.ForArtist(username)
+	if username == "" || diags.hasArtistErrors() {
+		return ArtistDB{}, diags
+	}
+
+	socialModels := make([]SocialDB, 0, len(socials))
 	for _, social := range socials {
-		socialMarshaled, err := social.Marshal()
-		if err != nil {
-			slog.Error("can't marshal social", "artist", username, "error", err.Error(), "social", social)
-			continue
-		}
-		socialsMarshaled = append(socialsMarshaled, socialMarshaled)
+		socialModels = append(socialModels, social.ToModel(username, len(socialModels)))
 	}
 
-	return ArtistDB{
+	artistModel := ArtistDB{
 		ID:          username,
 		DisplayName: displayName,
 		Avatar:      avatar,
-		Socials:     strings.Join(socialsMarshaled, "\n"),
-		Aliases:     alias,
-	}, nil
+		Socials:     socialModels,
+		Aliases:     artist.Aliases,
+	}
+	artistModel.Hash = artistModel.ComputeHash()
+	return artistModel, diags
+}
+
+// Format writes the artist back in the canonical layout: lowercase username,
+// _ placeholders, sorted aliases and the * marker in front of the social.
+// Comments are kept where they were.
+func (artist *Artist) Format() string {
+	header := []string{quoteField(artist.Username, ',')}
+	var displayName string
+	switch artist.DisplayName {
+	case "":
+		displayName = "_"
+	case "_":
+		displayName = `"_"`
+	default:
+		displayName = quoteField(artist.DisplayName, ',')
+	}
+	avatar := quoteField(artist.Avatar, ',')
+	aliases := make([]string, 0, len(artist.Aliases))
+	for _, alias := range artist.Aliases {
+		aliases = append(aliases, quoteField(alias, ','))
+	}
+	sort.Strings(aliases)
+
+	switch {
+	case len(aliases) > 0:
+		if avatar == "" {
+			avatar = "_"
+		}
+		header = append(header, displayName, avatar)
+		header = append(header, aliases...)
+	case avatar != "":
+		header = append(header, displayName, avatar)
+	case displayName != "_":
+		header = append(header, displayName)
+	}
+
+	lines := append([]string{}, artist.Comments...)
+	lines = append(lines, withComment(strings.Join(header, ","), artist.HeaderComment))
+	for _, social := range artist.Socials {
+		lines = append(lines, social.Format())
+	}
+	lines = append(lines, artist.TrailingComments...)
+	return strings.Join(lines, "\n")
+}
+
+// inferAvatar returns the avatar link of the first social that has one, the
+// socials are resolved so their handles are normalized
+func inferAvatar(appState *utils.AppState, socials []Social) string {
+	for _, social := range socials {
+		provider, ok := appState.SupportedSocials.Provider(social.SocialCode)
+		if !ok {
+			continue
+		}
+		result, err := provider.AvatarURL(social.Username)
+		if err != nil {
+			continue
+		}
+		return result
+	}
+	return ""
 }
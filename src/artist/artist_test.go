@@ -0,0 +1,117 @@
+package artist
+
+import (
+	"artistdb-go/src/utils"
+	"reflect"
+	"testing"
+)
+
+func TestUnmarshalErrorScope(t *testing.T) {
+	appState := &utils.AppState{
+		SupportedSocials: utils.NewSocialDBInstance(),
+		ReservedSegments: []string{"admin", "api"},
+	}
+	tests := []struct {
+		name string
+		raw  string
+		// an error skips the whole artist in lenient mode
+		artistError bool
+		// socials left once the broken ones are skipped, if the artist isn't
+		socials int
+	}{
+		{"valid", "paul\npaul@x\n//example.com/paul,Website\n", false, 2},
+		{"unknown social code", "paul\npaul@x\npaul@nosuchsocial\n", false, 1},
+		{"custom link without description", "paul\npaul@x\n//example.com/paul\n", false, 1},
+		{"fediverse handle", "paul,Paul,paul@mastodon.art@mastodon\npaul@mastodon.art@mastodon\n", false, 1},
+		{"fediverse handle without instance", "paul\npaul@x\npaul@mastodon\n", false, 1},
+		{"invalid handle", "paul\npaul@x\npaul_art@tumblr\n", false, 1},
+		{"no socials", "paul,Paul\n", true, 0},
+		{"empty username", ",Paul\npaul@x\n", true, 0},
+		{"reserved username", "admin\nadmin@x\n", true, 0},
+		{"reserved alias", "paul,Paul,_,api\npaul@x\n", true, 0},
+		{"unsafe alias", "paul,Paul,_,paul/art\npaul@x\n", true, 0},
+		{"unknown avatar social", "paul,Paul,paul@nosuchsocial\npaul@x\n", true, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			artist := Artist{}
+			model, diags := artist.Unmarshal(appState, NewParseState(), test.raw, 1)
+			if got := diags.hasArtistErrors(); got != test.artistError {
+				t.Errorf("hasArtistErrors() = %v, want %v, diagnostics %v", got, test.artistError, diags)
+			}
+			if !test.artistError && len(model.Socials) != test.socials {
+				t.Errorf("%d socials, want %d", len(model.Socials), test.socials)
+			}
+		})
+	}
+}
+
+func TestParseSourcesLenient(t *testing.T) {
+	// amy has no socials and an unsafe alias, so she's skipped and her alias
+	// is free for the artist after her
+	sources := []Source{{
+		Name:    "artists.txt",
+		Content: "paul\npaul@x\npaul@nosuchsocial\n//example.com/paul\n\namy,Amy,_,ami,a/b\n\nami\nami@x\n",
+	}}
+
+	t.Setenv("PARSE_MODE", "strict")
+	if artists, parsed := ParseSources(utils.NewParseAppState(), sources); !parsed.Diagnostics.HasErrors() || len(parsed.Skipped) != 0 {
+		t.Errorf("strict ParseSources() = %d artists, %v, want errors and nothing skipped", len(artists), parsed.Diagnostics)
+	}
+
+	t.Setenv("PARSE_MODE", "lenient")
+	artists, parsed := ParseSources(utils.NewParseAppState(), sources)
+	if parsed.Diagnostics.HasErrors() {
+		t.Fatalf("lenient ParseSources() found errors: %v", parsed.Diagnostics)
+	}
+	ids := make([]string, 0, len(artists))
+	for _, artist := range artists {
+		ids = append(ids, artist.ID)
+	}
+	if !reflect.DeepEqual(ids, []string{"ami", "paul"}) {
+		t.Fatalf("lenient ParseSources() kept %v, want [ami paul]", ids)
+	}
+	if socials := len(artists[1].Socials); socials != 1 {
+		t.Errorf("paul has %d socials, want 1", socials)
+	}
+
+	// the errors keep their severity, and each artist or social line is
+	// skipped once
+	for _, diag := range parsed.Diagnostics {
+		if diag.Severity == SeverityError && !diag.Skipped {
+			t.Errorf("error not flagged as skipped: %v", diag)
+		}
+	}
+	if errors := parsed.Diagnostics.Count(SeverityError); errors != 5 {
+		t.Errorf("lenient ParseSources() = %d errors, want 5: %v", errors, parsed.Diagnostics)
+	}
+	want := []Skipped{
+		{Code: CODE_UNKNOWN_SOCIAL, Kind: "social", Artist: "paul", Position: Position{File: "artists.txt", Line: 3, Column: 1}},
+		{Code: CODE_LINK_NEEDS_DESC, Kind: "social", Artist: "paul", Position: Position{File: "artists.txt", Line: 4, Column: 1}},
+		{Code: CODE_NO_SOCIALS, Kind: "artist", Artist: "amy", Position: Position{File: "artists.txt", Line: 6, Column: 1}},
+	}
+	for i := range parsed.Skipped {
+		parsed.Skipped[i].Reason = ""
+	}
+	if !reflect.DeepEqual(parsed.Skipped, want) {
+		t.Errorf("lenient ParseSources() skipped %+v, want %+v", parsed.Skipped, want)
+	}
+}
+
+func TestParseCommentAfterText(t *testing.T) {
+	artist := Artist{}
+	diags := artist.Parse("paul,Paul #1\npaul@x # main\n//example.com/paul,Prints #2\n", 1)
+	positions := make([]Position, 0)
+	for _, diag := range diags {
+		if diag.Code == CODE_COMMENT_AFTER_TEXT {
+			positions = append(positions, diag.Position)
+		}
+	}
+	want := []Position{{Line: 1, Column: 6}, {Line: 3, Column: 20}}
+	if !reflect.DeepEqual(positions, want) {
+		t.Errorf("Parse() warned at %v, want %v: %v", positions, want, diags)
+	}
+	if artist.DisplayName != "Paul" || artist.HeaderComment != "#1" {
+		t.Errorf("Parse() = %q with comment %q, want Paul with #1", artist.DisplayName, artist.HeaderComment)
+	}
+}
@@ -0,0 +1,128 @@
+package artist
+
+// Code identifies a kind of diagnostic. Codes are stable, tools should match on
+// them rather than on the messages. A Code is also an error, so
+// errors.Is(err, CODE_DUPLICATE_ALIAS) finds a diagnostic with that code.
+type Code string
+
+func (code Code) Error() string {
+	return string(code)
+}
+
+const (
+	CODE_DUPLICATE_ALIAS       Code = "E001"
+	CODE_DUPLICATE_USERNAME    Code = "E002"
+	CODE_USERNAME_IS_ALIAS     Code = "E003"
+	CODE_ALIAS_IS_USERNAME     Code = "E004"
+	CODE_EMPTY_USERNAME        Code = "E005"
+	CODE_UNCLOSED_QUOTE        Code = "E006"
+	CODE_NO_SOCIALS            Code = "E007"
+	CODE_WRONG_SOCIAL_FORMAT   Code = "E008"
+	CODE_LINK_NEEDS_DESC       Code = "E009"
+	CODE_UNKNOWN_SOCIAL        Code = "E010"
+	CODE_NO_DESCRIPTION        Code = "E011"
+	CODE_WRONG_AVATAR_FORMAT   Code = "E012"
+	CODE_UNKNOWN_AVATAR_SOCIAL Code = "E013"
+	CODE_CANT_INFER_AVATAR     Code = "E014"
+	CODE_RESERVED_USERNAME     Code = "E015"
+	CODE_UNSAFE_USERNAME       Code = "E016"
+	CODE_INVALID_HANDLE        Code = "E017"
+
+	CODE_REPEATED_ALIAS      Code = "W001"
+	CODE_CONFUSABLE_USERNAME Code = "W002"
+	CODE_PROFILE_LINK        Code = "W003"
+	CODE_DEPRECATED_SOCIAL   Code = "W004"
+	CODE_COMMENT_AFTER_TEXT  Code = "W005"
+)
+
+// CodeInfo documents a Code
+type CodeInfo struct {
+	Code     Code     `json:"code"`
+	Severity Severity `json:"severity"`
+	// short name of the problem
+	Title string `json:"title"`
+	// message of the diagnostics with this code
+	Message string `json:"message"`
+	// how to fix it
+	Help string `json:"help"`
+}
+
+// Catalog documents every Code, in order
+var Catalog = []CodeInfo{
+	{CODE_DUPLICATE_ALIAS, SeverityError, "duplicate alias",
+		"alias is already used by another artist",
+		"remove the alias from one of the artists"},
+	{CODE_DUPLICATE_USERNAME, SeverityError, "duplicate username",
+		"username is already used by another artist",
+		"merge the two artists or rename one of them"},
+	{CODE_USERNAME_IS_ALIAS, SeverityError, "username used as an alias",
+		"username is already an alias of another artist",
+		"remove the alias from the other artist or rename this one"},
+	{CODE_ALIAS_IS_USERNAME, SeverityError, "alias used as a username",
+		"alias is already the username of another artist",
+		"remove the alias, it would hide the other artist"},
+	{CODE_EMPTY_USERNAME, SeverityError, "empty username",
+		"username is empty",
+		"start the artist with its username, e.g. paul,Paul"},
+	{CODE_UNCLOSED_QUOTE, SeverityError, "unclosed quote",
+		"double quote is never closed",
+		`close the quote, a double quote inside a quoted field is written twice ("")`},
+	{CODE_NO_SOCIALS, SeverityError, "no socials",
+		"artist has no socials",
+		"add at least one social line below the artist"},
+	{CODE_WRONG_SOCIAL_FORMAT, SeverityError, "wrong social format",
+		WRONG_SOCIAL_FORMAT,
+		`quote the description if it contains a comma, e.g. paul@x,"Art, mostly"`},
+	{CODE_LINK_NEEDS_DESC, SeverityError, "custom link without description",
+		"custom social link needs a description",
+		"add a description after the link, e.g. //example.com/paul,Website"},
+	{CODE_UNKNOWN_SOCIAL, SeverityError, "unknown social code",
+		"social code not found to create profile link",
+		"use one of the supported social codes, or a custom //link with a description"},
+	{CODE_NO_DESCRIPTION, SeverityError, "no description",
+		"social has no description and no name to use instead",
+		"add a description to the social"},
+	{CODE_WRONG_AVATAR_FORMAT, SeverityError, "wrong avatar format",
+		WRONG_AVATAR_FORMAT,
+		"use username@socialcode, an absolute path in AVATAR_DIR, or _ to infer it"},
+	{CODE_UNKNOWN_AVATAR_SOCIAL, SeverityError, "avatar social not supported",
+		"social code not found to create avatar link",
+		"use a social with avatars, see the README, or _ to infer it from the socials"},
+	{CODE_CANT_INFER_AVATAR, SeverityError, "avatar can't be inferred",
+		"could not infer avatar from socials",
+		"add a social with avatars, or set the avatar explicitly"},
+	{CODE_RESERVED_USERNAME, SeverityError, "reserved username",
+		"username or alias is taken by a route of the server",
+		"rename it, the reserved names are listed in the README"},
+	{CODE_UNSAFE_USERNAME, SeverityError, "unsafe username",
+		"username or alias contains characters that aren't URL-safe",
+		"use only letters, digits, -, ., _ and ~"},
+	{CODE_INVALID_HANDLE, SeverityError, "invalid handle",
+		"handle isn't valid for the social",
+		"copy the handle from the profile URL of the social"},
+	{CODE_REPEATED_ALIAS, SeverityWarning, "repeated alias",
+		"alias repeated in the same artist",
+		"remove the repeated alias"},
+	{CODE_CONFUSABLE_USERNAME, SeverityWarning, "confusable username",
+		"username or alias looks identical to another one",
+		"rename one of them so visitors can tell them apart"},
+	{CODE_PROFILE_LINK, SeverityWarning, "profile as a custom link",
+		"custom link is the profile of a supported social",
+		"write it as username@socialcode to get the social's name and avatar, artistdb-go fmt -rewrite-links does it"},
+	{CODE_DEPRECATED_SOCIAL, SeverityWarning, "deprecated social code",
+		"social code is a deprecated alias",
+		"use the social code it's an alias of, artistdb-go fmt rewrites it"},
+	{CODE_COMMENT_AFTER_TEXT, SeverityWarning, "comment after a text field",
+		"trailing comment right after a display name or description, it could be part of it",
+		`quote the field if # is part of it, e.g. paul,"Paul #1"`},
+}
+
+// Info returns the catalog entry of the code
+func (code Code) Info() CodeInfo {
+	for _, info := range Catalog {
+		if info.Code == code {
+			return info
+		}
+	}
+	return CodeInfo{Code: code, Severity: SeverityError, Message: string(code)}
+}
@@ -0,0 +1,45 @@
+package artist
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCatalog(t *testing.T) {
+	seen := make(map[Code]bool, len(Catalog))
+	for _, info := range Catalog {
+		t.Run(string(info.Code), func(t *testing.T) {
+			if seen[info.Code] {
+				t.Errorf("%s is in the catalog twice", info.Code)
+			}
+			seen[info.Code] = true
+			wantSeverity := SeverityError
+			if strings.HasPrefix(string(info.Code), "W") {
+				wantSeverity = SeverityWarning
+			}
+			if info.Severity != wantSeverity {
+				t.Errorf("%s has severity %s, want %s", info.Code, info.Severity, wantSeverity)
+			}
+			if info.Title == "" || info.Message == "" || info.Help == "" {
+				t.Errorf("%s is missing its title, message or help: %+v", info.Code, info)
+			}
+		})
+	}
+}
+
+func TestDiagnosticError(t *testing.T) {
+	diag := NewDiagnostic(CODE_UNKNOWN_SOCIAL, Position{File: "artists.txt", Line: 4, Column: 9}, "instagarm")
+	diag.Artist = "paul"
+	diag = diag.Suggest([]string{"instagram"})
+	want := `artists.txt:4:9: error E010: social code not found to create profile link (artist paul, token "instagarm"), did you mean instagram?`
+	if got := diag.Error(); got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	if !errors.Is(diag, CODE_UNKNOWN_SOCIAL) || errors.Is(diag, CODE_UNKNOWN_AVATAR_SOCIAL) {
+		t.Errorf("errors.Is doesn't match the diagnostic on its code only")
+	}
+	if diag := diag.Suggest(nil); diag.Suggestion != "did you mean instagram?" {
+		t.Errorf("Suggest(nil) changed the suggestion to %q", diag.Suggestion)
+	}
+}
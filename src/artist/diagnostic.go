@@ -0,0 +1,268 @@
+package artist
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Position points to a 1-based line and column in one of the artists files
+type Position struct {
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+// before reports whether pos comes before other in the files
+func (pos Position) before(other Position) bool {
+	if pos.File != other.File {
+		return pos.File < other.File
+	}
+	if pos.Line != other.Line {
+		return pos.Line < other.Line
+	}
+	return pos.Column < other.Column
+}
+
+// Diagnostic is a single problem found while parsing the artists file. It's an
+// error, see Code for matching it with errors.Is.
+type Diagnostic struct {
+	Code     Code
+	Severity Severity
+	Position Position
+	// username of the artist the problem was found in, empty if unknown
+	Artist string
+	// the offending part of the line, e.g. the alias or the social code
+	Token   string
+	Message string
+	// how to fix it, e.g. did you mean instagram?
+	Suggestion string
+	// the error didn't stop the parse, what it affects was left out in lenient
+	// mode, see Skipped
+	Skipped bool
+
+	// the problem only affects one social line, not the whole artist
+	socialScope bool
+}
+
+// NewDiagnostic creates a diagnostic with the severity and message of the code
+func NewDiagnostic(code Code, pos Position, token string) Diagnostic {
+	info := code.Info()
+	return Diagnostic{
+		Code:     code,
+		Severity: info.Severity,
+		Position: pos,
+		Token:    token,
+		Message:  info.Message,
+	}
+}
+
+// Suggest sets the suggestion to the closest candidates, if any
+func (diag Diagnostic) Suggest(candidates []string) Diagnostic {
+	if len(candidates) > 0 {
+		diag.Suggestion = "did you mean " + strings.Join(candidates, ", ") + "?"
+	}
+	return diag
+}
+
+// Error renders the diagnostic as plain text, e.g.
+//
+//	artists.txt:4:9: error E010: social code not found to create profile link (artist paul, token "instagarm"), did you mean instagram?
+func (diag Diagnostic) Error() string {
+	var text strings.Builder
+	if diag.Position.File != "" {
+		text.WriteString(diag.Position.File + ":")
+	}
+	fmt.Fprintf(&text, "%d:%d: %s %s: %s", diag.Position.Line, diag.Position.Column,
+		diag.Severity, diag.Code, diag.Message)
+	details := make([]string, 0, 2)
+	if diag.Artist != "" {
+		details = append(details, "artist "+diag.Artist)
+	}
+	if diag.Token != "" {
+		details = append(details, fmt.Sprintf("token %q", diag.Token))
+	}
+	if diag.Skipped {
+		details = append(details, "skipped")
+	}
+	if len(details) > 0 {
+		text.WriteString(" (" + strings.Join(details, ", ") + ")")
+	}
+	if diag.Suggestion != "" {
+		text.WriteString(", " + diag.Suggestion)
+	}
+	return text.String()
+}
+
+// Is reports whether target is the code of the diagnostic
+func (diag Diagnostic) Is(target error) bool {
+	code, ok := target.(Code)
+	return ok && code == diag.Code
+}
+
+// attrs returns the fields of the diagnostic other than the message and the
+// severity, leaving out the empty ones
+func (diag Diagnostic) attrs() []slog.Attr {
+	attrs := []slog.Attr{slog.String("code", string(diag.Code))}
+	if diag.Position.File != "" {
+		attrs = append(attrs, slog.String("file", diag.Position.File))
+	}
+	attrs = append(attrs,
+		slog.Int("line", diag.Position.Line),
+		slog.Int("col", diag.Position.Column))
+	if diag.Artist != "" {
+		attrs = append(attrs, slog.String("artist", diag.Artist))
+	}
+	if diag.Token != "" {
+		attrs = append(attrs, slog.String("token", diag.Token))
+	}
+	if diag.Suggestion != "" {
+		attrs = append(attrs, slog.String("suggestion", diag.Suggestion))
+	}
+	if diag.Skipped {
+		attrs = append(attrs, slog.Bool("skipped", true))
+	}
+	return attrs
+}
+
+// LogValue renders the diagnostic as a slog group
+func (diag Diagnostic) LogValue() slog.Value {
+	return slog.GroupValue(append([]slog.Attr{
+		slog.String("severity", string(diag.Severity)),
+		slog.String("message", diag.Message),
+	}, diag.attrs()...)...)
+}
+
+// Log writes the diagnostic to slog, as an error or a warning
+func (diag Diagnostic) Log() {
+	level := slog.LevelWarn
+	if diag.Severity == SeverityError {
+		level = slog.LevelError
+	}
+	slog.LogAttrs(context.Background(), level, diag.Message, diag.attrs()...)
+}
+
+func (diag Diagnostic) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Code       Code     `json:"code"`
+		Severity   Severity `json:"severity"`
+		Position   Position `json:"position"`
+		Artist     string   `json:"artist,omitempty"`
+		Token      string   `json:"token,omitempty"`
+		Message    string   `json:"message"`
+		Suggestion string   `json:"suggestion,omitempty"`
+		Skipped    bool     `json:"skipped,omitempty"`
+	}{diag.Code, diag.Severity, diag.Position, diag.Artist, diag.Token, diag.Message, diag.Suggestion, diag.Skipped})
+}
+
+// Diagnostics collects every problem found in one parse instead of stopping at
+// the first one
+type Diagnostics []Diagnostic
+
+func (diags *Diagnostics) Add(diag Diagnostic) {
+	*diags = append(*diags, diag)
+}
+
+// HasErrors reports whether an error stops the parse, the ones skipped in
+// lenient mode don't
+func (diags Diagnostics) HasErrors() bool {
+	for _, diag := range diags {
+		if diag.Severity == SeverityError && !diag.Skipped {
+			return true
+		}
+	}
+	return false
+}
+
+// hasArtistErrors reports whether an error affects the whole artist rather
+// than one of its social lines
+func (diags Diagnostics) hasArtistErrors() bool {
+	for _, diag := range diags {
+		if diag.Severity == SeverityError && !diag.socialScope {
+			return true
+		}
+	}
+	return false
+}
+
+func (diags Diagnostics) Count(severity Severity) int {
+	count := 0
+	for _, diag := range diags {
+		if diag.Severity == severity {
+			count++
+		}
+	}
+	return count
+}
+
+// Err returns the errors that stop the parse as one error, nil if there's none.
+// errors.Is and errors.As look into every one of them.
+func (diags Diagnostics) Err() error {
+	errs := make(Diagnostics, 0)
+	for _, diag := range diags {
+		if diag.Severity == SeverityError && !diag.Skipped {
+			errs = append(errs, diag)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// Error renders the diagnostics as plain text, one per line
+func (diags Diagnostics) Error() string {
+	lines := make([]string, 0, len(diags))
+	for _, diag := range diags {
+		lines = append(lines, diag.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (diags Diagnostics) Unwrap() []error {
+	errs := make([]error, 0, len(diags))
+	for _, diag := range diags {
+		errs = append(errs, diag)
+	}
+	return errs
+}
+
+// Sort orders the diagnostics by their position in the files
+func (diags Diagnostics) Sort() {
+	sort.SliceStable(diags, func(i, j int) bool {
+		return diags[i].Position.before(diags[j].Position)
+	})
+}
+
+// Log writes every diagnostic to slog, in order
+func (diags Diagnostics) Log() {
+	for _, diag := range diags {
+		diag.Log()
+	}
+}
+
+// InFile sets the file of every diagnostic
+func (diags Diagnostics) InFile(file string) {
+	for i := range diags {
+		diags[i].Position.File = file
+	}
+}
+
+// ForArtist sets the artist of every diagnostic that doesn't have one yet
+func (diags Diagnostics) ForArtist(username string) {
+	for i := range diags {
+		if diags[i].Artist == "" {
+			diags[i].Artist = username
+		}
+	}
+}
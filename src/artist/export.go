@@ -0,0 +1,179 @@
+package artist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/uptrace/bun"
+)
+
+const exportTmpPrefix = ".tmp-"
+
+// EXPORT_MANIFEST lists the files Export wrote in outDir, one per line. Only
+// these are ever removed, other files in outDir are left alone.
+const EXPORT_MANIFEST = ".artistdb-export"
+
+// Export writes the artists and aliases in the database to outDir: one file per
+// username containing
+//
+//	displayName,avatar
+//	socialLink,description
+//
+// and one file per alias containing @username. Files are replaced atomically,
+// and files of artists or aliases that no longer exist are removed if the
+// previous export wrote them, see EXPORT_MANIFEST.
+func Export(ctx context.Context, db *bun.DB, outDir string) (int, error) {
+	artists := make([]ArtistDB, 0)
+	if err := db.NewSelect().
+		Model(&artists).
+		Relation("Socials", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Order("position")
+		}).
+		Scan(ctx); err != nil {
+		return 0, fmt.Errorf("Export: can't select artists: %w", err)
+	}
+	aliases := make([]AliasDB, 0)
+	if err := db.NewSelect().Model(&aliases).Scan(ctx); err != nil {
+		return 0, fmt.Errorf("Export: can't select aliases: %w", err)
+	}
+
+	files := make(map[string]string, len(artists)+len(aliases))
+	for _, artist := range artists {
+		content := quoteField(artist.DisplayName, ',') + "," + quoteField(exportAvatar(artist.Avatar), ',') + "\n"
+		for _, social := range artist.Socials {
+			socialMarshaled, err := social.Marshal()
+			if err != nil {
+				return 0, fmt.Errorf("Export: artist %s: %w", artist.ID, err)
+			}
+			content += socialMarshaled + "\n"
+		}
+		files[artist.ID] = content
+	}
+	for _, alias := range aliases {
+		if alias.Alias == alias.ID {
+			continue
+		}
+		files[alias.Alias] = "@" + alias.ID + "\n"
+	}
+
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return 0, fmt.Errorf("Export: %w", err)
+	}
+	previous, err := readExportManifest(outDir)
+	if err != nil {
+		return 0, fmt.Errorf("Export: %w", err)
+	}
+	written := 0
+	exported := make([]string, 0, len(files))
+	for name, content := range files {
+		if !isSafeFileName(name) {
+			slog.Warn("Export: can't be used as a file name, skipped", "name", name)
+			continue
+		}
+		changed, err := writeFileAtomic(filepath.Join(outDir, name), content)
+		if err != nil {
+			return written, fmt.Errorf("Export: %w", err)
+		}
+		if changed {
+			written++
+		}
+		exported = append(exported, name)
+	}
+
+	// remove files of deleted artists & aliases, the manifest is written first
+	// so a failed removal is retried on the next export
+	sort.Strings(exported)
+	manifest := strings.Join(exported, "\n")
+	if len(exported) > 0 {
+		manifest += "\n"
+	}
+	if _, err := writeFileAtomic(filepath.Join(outDir, EXPORT_MANIFEST), manifest); err != nil {
+		return written, fmt.Errorf("Export: %w", err)
+	}
+	for _, name := range previous {
+		if _, ok := files[name]; ok || !isSafeFileName(name) {
+			continue
+		}
+		err := os.Remove(filepath.Join(outDir, name))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return written, fmt.Errorf("Export: %w", err)
+		}
+		written++
+	}
+	return written, nil
+}
+
+// readExportManifest returns the files the previous export wrote, none if
+// outDir was never exported to
+func readExportManifest(outDir string) ([]string, error) {
+	rawBytes, err := os.ReadFile(filepath.Join(outDir, EXPORT_MANIFEST))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(rawBytes)), nil
+}
+
+// exportAvatar converts the avatar link in the database to the output format,
+// either social/username, microlink/profileLink or /filename.format
+func exportAvatar(avatar string) string {
+	switch {
+	case strings.HasPrefix(avatar, "//unavatar.io/"):
+		return strings.TrimPrefix(avatar, "//unavatar.io/")
+	case strings.HasPrefix(avatar, "//github.com/") && strings.HasSuffix(avatar, ".png"):
+		return "github/" + strings.TrimSuffix(strings.TrimPrefix(avatar, "//github.com/"), ".png")
+	case strings.HasPrefix(avatar, "/avatar/"):
+		return strings.TrimPrefix(avatar, "/avatar")
+	default:
+		return avatar
+	}
+}
+
+func isSafeFileName(name string) bool {
+	return name != "" &&
+		name != "." &&
+		name != ".." &&
+		name != EXPORT_MANIFEST &&
+		!strings.HasPrefix(name, exportTmpPrefix) &&
+		!strings.ContainsAny(name, "/\\\x00")
+}
+
+// writeFileAtomic writes content to a temp file next to path then renames it
+// over path, skipping the write if the content is unchanged
+func writeFileAtomic(path, content string) (bool, error) {
+	if existing, err := os.ReadFile(path); err == nil && string(existing) == content {
+		return false, nil
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), exportTmpPrefix+"*")
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(tmpFile.Name())
+	if _, err := tmpFile.WriteString(content); err != nil {
+		tmpFile.Close()
+		return false, err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return false, err
+	}
+	if err := os.Chmod(tmpFile.Name(), 0o644); err != nil {
+		return false, err
+	}
+	if err := os.Rename(tmpFile.Name(), path); err != nil {
+		return false, err
+	}
+	return true, nil
+}
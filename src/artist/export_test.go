@@ -0,0 +1,170 @@
+package artist
+
+import (
+	"artistdb-go/src/migrations"
+	"artistdb-go/src/utils"
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/sqlitedialect"
+	"github.com/uptrace/bun/driver/sqliteshim"
+)
+
+// newTestDB opens an empty in-memory database with the current schema
+func newTestDB(t *testing.T) *bun.DB {
+	t.Helper()
+	sqldb, err := sql.Open(sqliteshim.ShimName, utils.SQLiteDSN("file::memory:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every connection to :memory: is a database of its own
+	sqldb.SetMaxOpenConns(1)
+	db := bun.NewDB(sqldb, sqlitedialect.New())
+	t.Cleanup(func() { db.Close() })
+	if err := migrations.Create(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// readDir returns the content of the files in dir by name
+func readDir(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[entry.Name()] = string(content)
+	}
+	return files
+}
+
+func TestExport(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	outDir := t.TempDir()
+	// not written by Export, so never removed
+	if err := os.WriteFile(filepath.Join(outDir, "notes.md"), []byte("notes\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	artists := []ArtistDB{
+		{ID: "paul", DisplayName: "Paul, the Painter", Avatar: "//unavatar.io/x/paul"},
+		{ID: "amy", DisplayName: "Amy", Avatar: "/avatar/amy.png"},
+	}
+	socials := []SocialDB{
+		{ArtistID: "paul", Position: 1, Link: "//example.com/paul", Description: "Prints, stickers", IsSpecial: true},
+		{ArtistID: "paul", Position: 0, Link: "//x.com/paul", Description: "𝕏 | Life"},
+		{ArtistID: "amy", Position: 0, Link: "//instagram.com/amy", Description: "Instagram"},
+	}
+	aliases := []AliasDB{{Alias: "paul", ID: "paul"}, {Alias: "painter", ID: "paul"}, {Alias: "amy", ID: "amy"}}
+	if _, err := db.NewInsert().Model(&artists).Exec(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.NewInsert().Model(&socials).Exec(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.NewInsert().Model(&aliases).Exec(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	written, err := Export(ctx, db, outDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"paul":          "\"Paul, the Painter\",x/paul\n//x.com/paul,𝕏 | Life\n*//example.com/paul,\"Prints, stickers\"\n",
+		"amy":           "Amy,/amy.png\n//instagram.com/amy,Instagram\n",
+		"painter":       "@paul\n",
+		"notes.md":      "notes\n",
+		EXPORT_MANIFEST: "amy\npainter\npaul\n",
+	}
+	if got := readDir(t, outDir); !reflect.DeepEqual(got, want) {
+		t.Errorf("Export() wrote %v, want %v", got, want)
+	}
+	if written != 3 {
+		t.Errorf("Export() = %d, want 3 files written", written)
+	}
+
+	// unchanged files aren't rewritten, the files of removed artists and
+	// aliases are deleted, other files are left alone
+	for _, model := range []any{(*AliasDB)(nil), (*SocialDB)(nil)} {
+		if _, err := db.NewDelete().Model(model).Where("artist_id = ?", "amy").Exec(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.NewDelete().Model((*ArtistDB)(nil)).Where("id = ?", "amy").Exec(ctx); err != nil {
+		t.Fatal(err)
+	}
+	written, err = Export(ctx, db, outDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := readDir(t, outDir)
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	wantNames := []string{EXPORT_MANIFEST, "notes.md", "painter", "paul"}
+	if !reflect.DeepEqual(names, wantNames) || written != 1 {
+		t.Errorf("Export() = %d, left %v, want 1 change leaving %v", written, names, wantNames)
+	}
+	if got, want := files[EXPORT_MANIFEST], "painter\npaul\n"; got != want {
+		t.Errorf("manifest = %q, want %q", got, want)
+	}
+}
+
+func TestExportAvatar(t *testing.T) {
+	tests := []struct {
+		avatar string
+		want   string
+	}{
+		{"//unavatar.io/twitter/paul", "twitter/paul"},
+		{"//github.com/paul.png", "github/paul"},
+		{"//unavatar.io/microlink/mastodon.art/@paul", "microlink/mastodon.art/@paul"},
+		{"/avatar/paul.png", "/paul.png"},
+		{"", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.avatar, func(t *testing.T) {
+			if got := exportAvatar(test.avatar); got != test.want {
+				t.Errorf("exportAvatar(%q) = %q, want %q", test.avatar, got, test.want)
+			}
+		})
+	}
+}
+
+func TestIsSafeFileName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"paul", true},
+		{"paul.art", true},
+		{"", false},
+		{".", false},
+		{"..", false},
+		{"a/b", false},
+		{`a\b`, false},
+		{exportTmpPrefix + "paul", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := isSafeFileName(test.name); got != test.want {
+				t.Errorf("isSafeFileName(%q) = %v, want %v", test.name, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package artist
+
+import (
+	"artistdb-go/src/utils"
+	"sort"
+	"strings"
+)
+
+// FormatOptions are the rewrites of Format on top of the layout
+type FormatOptions struct {
+	// rewrite the custom links to the profile of a supported social into
+	// username@socialcode, see Social.RewriteProfileLink
+	RewriteLinks bool
+	// when set, the aliases of social codes are rewritten to the social code.
+	// Needed by RewriteLinks.
+	SupportedSocials *utils.SupportedSocials
+}
+
+// Format rewrites the artists file in the canonical layout, with the artists
+// sorted by username and exactly one blank line between them. Only the syntax
+// is checked, the returned diagnostics are the ones Artist.Parse found.
+//
+// A block made only of comments stays at the top if it's the first one in the
+// file, otherwise it's attached to the artist below it.
+func Format(artistString string, options FormatOptions) (string, Diagnostics) {
+	diags := make(Diagnostics, 0)
+	artists := make([]Artist, 0)
+	fileComments := make([]string, 0)
+	pendingComments := make([]string, 0)
+	for i, block := range SplitBlocks(artistString) {
+		artist := Artist{}
+		diags = append(diags, artist.Parse(block.Raw, block.StartLine)...)
+		if artist.Username == "" && len(artist.Socials) == 0 {
+			if i == 0 {
+				fileComments = artist.TrailingComments
+			} else {
+				pendingComments = append(pendingComments, artist.TrailingComments...)
+			}
+			continue
+		}
+		if options.RewriteLinks {
+			for i := range artist.Socials {
+				artist.Socials[i].RewriteProfileLink(options.SupportedSocials)
+			}
+		}
+		if options.SupportedSocials != nil {
+			artist.canonicalCodes(options.SupportedSocials)
+		}
+		artist.Comments = append(pendingComments, artist.Comments...)
+		pendingComments = make([]string, 0)
+		artists = append(artists, artist)
+	}
+	diags.Sort()
+
+	sort.SliceStable(artists, func(i, j int) bool {
+		return artists[i].Username < artists[j].Username
+	})
+	formatted := make([]string, 0, len(artists)+2)
+	if len(fileComments) > 0 {
+		formatted = append(formatted, strings.Join(fileComments, "\n"))
+	}
+	for _, artist := range artists {
+		formatted = append(formatted, artist.Format())
+	}
+	if len(pendingComments) > 0 {
+		formatted = append(formatted, strings.Join(pendingComments, "\n"))
+	}
+	if len(formatted) == 0 {
+		return "", diags
+	}
+	return strings.Join(formatted, "\n\n") + "\n", diags
+}
+
+// canonicalCodes rewrites the aliases of social codes in the socials and the
+// avatar to the social code
+func (artist *Artist) canonicalCodes(socials *utils.SupportedSocials) {
+	for i, social := range artist.Socials {
+		if canonical, _, ok := socials.Canonical(social.SocialCode); ok {
+			artist.Socials[i].SocialCode = canonical
+		}
+	}
+	if at := strings.LastIndexByte(artist.Avatar, '@'); at >= 0 {
+		if canonical, _, ok := socials.Canonical(artist.Avatar[at+1:]); ok {
+			artist.Avatar = artist.Avatar[:at+1] + canonical
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package artist
+
+import (
+	"artistdb-go/src/utils"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	socials := utils.NewSocialDBInstance()
+	tests := []struct {
+		name    string
+		input   string
+		options FormatOptions
+		want    string
+	}{
+		{
+			name:  "empty",
+			input: "\n\n",
+			want:  "",
+		},
+		{
+			name:  "sorted with one blank line between artists",
+			input: "zed,Zed\nzed@instagram,Art\n\n\n\npaul\npaul@twitter\n",
+			want:  "paul\npaul@twitter\n\nzed,Zed\nzed@instagram,Art\n",
+		},
+		{
+			name:  "spaces around fields are trimmed",
+			input: "paul , Paul\n  paul@twitter , Life  \n",
+			want:  "paul,Paul\npaul@twitter,Life\n",
+		},
+		{
+			name:  "quoted fields stay quoted",
+			input: "paul,\"Paul, the Painter\"\n*//example.com/paul,\"Prints, stickers & \"\"zines\"\"\"\n",
+			want:  "paul,\"Paul, the Painter\"\n*//example.com/paul,\"Prints, stickers & \"\"zines\"\"\"\n",
+		},
+		{
+			name:  "unneeded quotes are dropped",
+			input: "\"paul\",\"Paul\"\n\"paul@twitter\"\n",
+			want:  "paul,Paul\npaul@twitter\n",
+		},
+		{
+			name:  "comments stay with their artist",
+			input: "# file header\n\nzed\nzed@twitter\n\n# about paul\npaul # main\npaul@twitter # old\n\n# trailing\n",
+			want:  "# file header\n\n# about paul\npaul # main\npaul@twitter # old\n\nzed\nzed@twitter\n\n# trailing\n",
+		},
+		{
+			name:  "special socials keep their star",
+			input: "paul\n* //example.com/paul , Website\n",
+			want:  "paul\n*//example.com/paul,Website\n",
+		},
+		{
+			name:    "aliases of social codes are canonical",
+			input:   "paul,Paul,paul@twitter\npaul@twitter\npaul@fb,Art\n",
+			options: FormatOptions{SupportedSocials: socials},
+			want:    "paul,Paul,paul@x\npaul@x\npaul@facebook,Art\n",
+		},
+		{
+			name:    "profile links are rewritten",
+			input:   "paul\n//www.instagram.com/paul/,Instagram\nhttps://x.com/paul,Art\n//example.com/paul,Website\n",
+			options: FormatOptions{RewriteLinks: true, SupportedSocials: socials},
+			want:    "paul\npaul@instagram\npaul@x,Art\n//example.com/paul,Website\n",
+		},
+		{
+			name:  "profile links are kept without RewriteLinks",
+			input: "paul\n//www.instagram.com/paul/,Instagram\n",
+			want:  "paul\n//www.instagram.com/paul/,Instagram\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, diags := Format(test.input, test.options)
+			if diags.HasErrors() {
+				t.Fatalf("Format(%q) found errors: %v", test.input, diags)
+			}
+			if got != test.want {
+				t.Errorf("Format(%q) = %q, want %q", test.input, got, test.want)
+			}
+			if again, _ := Format(got, test.options); again != got {
+				t.Errorf("Format isn't idempotent: %q became %q", got, again)
+			}
+		})
+	}
+}
+
+func TestFormatKeepsBrokenSocials(t *testing.T) {
+	got, diags := Format("paul\n  wrong social  \npaul@twitter\n", FormatOptions{})
+	if want := "paul\nwrong social\npaul@twitter\n"; got != want {
+		t.Errorf("Format() = %q, want %q", got, want)
+	}
+	if diags.Count(SeverityError) != 1 || diags.hasArtistErrors() {
+		t.Errorf("Format() = %v, want an error for the broken social only", diags)
+	}
+}
+
+func TestFormatReportsArtistsWithoutSocials(t *testing.T) {
+	if _, diags := Format("paul,Paul\n", FormatOptions{}); !diags.HasErrors() {
+		t.Errorf("Format() found no error in an artist without socials")
+	}
+}
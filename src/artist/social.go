@@ -4,6 +4,8 @@ import (
 	"artistdb-go/src/utils"
 	"fmt"
 	"strings"
+
+	"github.com/uptrace/bun"
 )
 
 type Social struct {
@@ -12,92 +14,282 @@ type Social struct {
 	Description string
 	Link        string
 	IsSpecial   bool
+
+	// the line as written in the artists file
+	Raw string
+	// the social line is prefixed with *
+	HasMarker bool
+	Position  Position
+	// whole-line comments above the line
+	Comments []string
+	// trailing comment of the line
+	Comment string
+
+	codePos Position
+	linkPos Position
 }
 
-var WRONG_SOCIAL_FORMAT = "social must have a format of username@socialcode[,description] or //link,description"
+// SocialDB is one resolved social of an artist, Position keeps the order they
+// were written in
+type SocialDB struct {
+	bun.BaseModel `bun:"table:social"`
 
-func (social *Social) Unmarshal(
-	appState *utils.AppState,
-	username, rawString string,
-) *SlogErr {
-	social.IsSpecial = false
+	ID          int64  `bun:"id,pk,autoincrement"`
+	ArtistID    string `bun:"artist_id,notnull"`
+	Position    int    `bun:"position,notnull"`
+	SocialCode  string `bun:"social_code"`
+	Handle      string `bun:"handle"`
+	Link        string `bun:"link,notnull"`
+	Description string `bun:"description,notnull"`
+	IsSpecial   bool   `bun:"is_special,notnull"`
+}
 
-	// <link || username@socialcode>,description
-	slice := strings.Split(rawString, ",")
-	if len(slice) < 1 || len(slice) > 2 {
-		return NewSlogErr(
-			"Social.Unmarshal: "+WRONG_SOCIAL_FORMAT,
-			"artist", username, "social", rawString, "kind", "split with ,", "split result", slice)
+var WRONG_SOCIAL_FORMAT = "social must have a format of username@socialcode[,description] or //link,description"
+
+// Parse reads the social line without resolving the social code, pos is where
+// the line starts in the artists file. Description is the one written in the
+// file until Resolve is called.
+func (social *Social) Parse(username, rawString string, pos Position) *Diagnostic {
+	*social = Social{Raw: rawString, Position: pos}
+	at := func(f field) Position {
+		return Position{Line: pos.Line, Column: pos.Column + f.Column - 1}
+	}
+	newDiag := func(code Code, f field, token string) *Diagnostic {
+		diag := NewDiagnostic(code, at(f), token)
+		diag.Artist = username
+		return &diag
 	}
 
 	// *<link || username@socialcode>,description
-	if strings.HasPrefix(slice[0], "*") {
+	line := strings.TrimSpace(rawString)
+	lineColumn := strings.Index(rawString, line) + 1
+	if strings.HasPrefix(line, "*") {
+		social.HasMarker = true
 		social.IsSpecial = true
-		slice[0] = strings.TrimPrefix(slice[0], "*")
+		trimmed := strings.TrimLeft(strings.TrimPrefix(line, "*"), " \t,")
+		lineColumn += len(line) - len(trimmed)
+		line = trimmed
 	}
 
-	usingCustomLink := strings.HasPrefix(slice[0], "//")
-	usingAtSocial := strings.Contains(slice[0], "@")
+	// <link || username@socialcode>,description
+	slice := splitFields(line, ',')
+	for i := range slice {
+		slice[i].Column += lineColumn - 1
+		if slice[i].Unclosed {
+			return newDiag(CODE_UNCLOSED_QUOTE, slice[i], slice[i].Value)
+		}
+	}
+	if len(slice) > 2 {
+		return newDiag(CODE_WRONG_SOCIAL_FORMAT, slice[0], line)
+	}
+
+	// a pasted link keeps working, the scheme is dropped as the page adds https:
+	for _, scheme := range []string{"https://", "http://"} {
+		if len(slice[0].Value) > len(scheme) && strings.EqualFold(slice[0].Value[:len(scheme)], scheme) {
+			slice[0].Value = "//" + slice[0].Value[len(scheme):]
+			break
+		}
+	}
+	usingCustomLink := strings.HasPrefix(slice[0].Value, "//")
+	usingAtSocial := strings.Contains(slice[0].Value, "@")
+	if len(slice) == 2 {
+		social.Description = slice[1].Value
+	}
 
 	switch {
 	case usingCustomLink:
-		if len(slice) < 2 {
-			return NewSlogErr(
-				"Social.Unmarshal: custom social link needs a description",
-				"artist", username, "social", rawString)
+		if social.Description == "" {
+			return newDiag(CODE_LINK_NEEDS_DESC, slice[0], slice[0].Value)
 		}
-		social.Link = slice[0]
-		social.Description = slice[1]
+		social.Link = slice[0].Value
+		social.linkPos = at(slice[0])
 	case usingAtSocial:
-		// username@socialcode
-		subSlice := strings.Split(slice[0], "@")
-		if len(subSlice) != 2 {
-			return NewSlogErr(
-				"Social.Unmarshal: "+WRONG_SOCIAL_FORMAT,
-				"artist", username, "social", rawString, "kind", "split with @", "split result", subSlice)
-		}
-		social.Username = subSlice[0]
-		social.SocialCode = subSlice[1]
-		if appState.SupportedSocials.IsSpecial(social.SocialCode) {
-			social.IsSpecial = true
+		// username@socialcode, the username of fediverse socials has the
+		// instance too, e.g. paul@mastodon.art@mastodon
+		subSlice := splitFields(slice[0].Value, '@')
+		last := len(subSlice) - 1
+		handle := make([]string, 0, last)
+		for _, f := range subSlice[:last] {
+			handle = append(handle, f.Value)
 		}
+		social.Username = strings.Join(handle, "@")
+		social.SocialCode = strings.ToLower(subSlice[last].Value)
+		social.codePos = at(field{Column: slice[0].Column + subSlice[last].Column - 1})
+	default:
+		return newDiag(CODE_WRONG_SOCIAL_FORMAT, slice[0], slice[0].Value)
+	}
+	return nil
+}
 
-		socialLink, err := appState.SupportedSocials.
-			ToProfileLink(social.Username, social.SocialCode)
-		if err != nil {
-			return NewSlogErr(
-				"Social.Unmarshal: "+err.Error(),
-				"artist", username, "socialCode", social.SocialCode)
-		}
-		social.Link = socialLink
+// CodePosition is where the social code starts in the artists file, the zero
+// Position for a custom link
+func (social *Social) CodePosition() Position {
+	return social.codePos
+}
 
-		var description string
-		if len(slice) == 2 {
-			description = slice[1]
+// Resolve turns a parsed username@socialcode into its profile link and
+// formatted description
+func (social *Social) Resolve(appState *utils.AppState, username string) *Diagnostic {
+	if social.SocialCode == "" {
+		return nil
+	}
+	newDiag := func(code Code) *Diagnostic {
+		diag := NewDiagnostic(code, social.codePos, social.SocialCode)
+		diag.Artist = username
+		return &diag
+	}
+
+	if appState.SupportedSocials.IsSpecial(social.SocialCode) {
+		social.IsSpecial = true
+	}
+
+	provider, ok := appState.SupportedSocials.Provider(social.SocialCode)
+	if !ok {
+		diag := newDiag(CODE_UNKNOWN_SOCIAL).
+			Suggest(utils.Suggest(social.SocialCode, appState.SupportedSocials.Codes()))
+		return &diag
+	}
+	handle := provider.NormalizeHandle(social.Username)
+	if err := provider.ValidateHandle(handle); err != nil {
+		diag := newDiag(CODE_INVALID_HANDLE)
+		diag.Token = social.Username
+		diag.Message += ", " + err.Error()
+		return diag
+	}
+	socialLink, err := provider.ProfileURL(handle)
+	if err != nil {
+		diag := newDiag(CODE_INVALID_HANDLE)
+		diag.Message += ", " + err.Error()
+		return diag
+	}
+	social.Username = handle
+	social.SocialCode, _, _ = appState.SupportedSocials.Canonical(social.SocialCode)
+	social.Link = socialLink
+
+	description, err := appState.SupportedSocials.
+		FormatDescription(social.SocialCode, social.Description)
+	if err != nil {
+		return newDiag(CODE_NO_DESCRIPTION)
+	}
+	social.Description = description
+	return nil
+}
+
+// checkDeprecatedCode warns about a deprecated alias of a social code, for a
+// social or an avatar
+func checkDeprecatedCode(socials *utils.SupportedSocials, socialCode string, pos Position) *Diagnostic {
+	canonical, deprecated, _ := socials.Canonical(socialCode)
+	if !deprecated {
+		return nil
+	}
+	diag := NewDiagnostic(CODE_DEPRECATED_SOCIAL, pos, socialCode)
+	diag.Suggestion = fmt.Sprintf("use %s", canonical)
+	return &diag
+}
+
+// checkProfileLink warns about a custom link to the profile of a supported
+// social, which loses its name and avatar
+func (social *Social) checkProfileLink(socials *utils.SupportedSocials, username string) *Diagnostic {
+	if social.SocialCode != "" || social.Link == "" {
+		return nil
+	}
+	handle, socialCode, ok := socials.MatchProfileURL(social.Link)
+	if !ok {
+		return nil
+	}
+	diag := NewDiagnostic(CODE_PROFILE_LINK, social.linkPos, social.Link)
+	diag.Artist = username
+	diag.Suggestion = fmt.Sprintf("use %s@%s", handle, socialCode)
+	return &diag
+}
+
+// RewriteProfileLink turns a custom link to the profile of a supported social
+// into username@socialcode, and reports whether it did. A description that's
+// only the name of the social is dropped, the name is prepended anyway.
+func (social *Social) RewriteProfileLink(socials *utils.SupportedSocials) bool {
+	if social.SocialCode != "" || social.Link == "" {
+		return false
+	}
+	handle, socialCode, ok := socials.MatchProfileURL(social.Link)
+	if !ok {
+		return false
+	}
+	social.Username, social.SocialCode, social.Link = handle, socialCode, ""
+	if strings.EqualFold(social.Description, socials.DisplayName(socialCode)) {
+		social.Description = ""
+	}
+	return true
+}
+
+// Unmarshal parses and resolves one social line, pos is where the line starts
+// in the artists file
+func (social *Social) Unmarshal(
+	appState *utils.AppState,
+	username, rawString string,
+	pos Position,
+) *Diagnostic {
+	if diag := social.Parse(username, rawString, pos); diag != nil {
+		return diag
+	}
+	return social.Resolve(appState, username)
+}
+
+// Format writes a parsed social line back in the canonical layout, with its
+// comments. Lines that couldn't be parsed are kept as written.
+func (social *Social) Format() string {
+	var line string
+	switch {
+	case social.SocialCode != "":
+		line = quoteField(social.Username+"@"+social.SocialCode, ',')
+	case social.Link != "":
+		line = quoteField(social.Link, ',')
+	}
+	if line == "" {
+		line = strings.TrimSpace(social.Raw)
+	} else {
+		if social.Description != "" {
+			line += "," + quoteField(social.Description, ',')
 		}
-		description, err = appState.SupportedSocials.
-			FormatDescription(social.SocialCode, description)
-		if err != nil {
-			return NewSlogErr(
-				"Social.Unmarshal: "+err.Error(),
-				"artist", username, "socialCode", social.SocialCode)
+		if social.HasMarker {
+			line = "*" + line
 		}
-		social.Description = description
-	default:
-		return NewSlogErr(
-			"Social.Unmarshal: "+WRONG_SOCIAL_FORMAT,
-			"artist", username, "social", rawString, "kind", "not using @ or //")
 	}
-	return nil
+	lines := append([]string{}, social.Comments...)
+	lines = append(lines, withComment(line, social.Comment))
+	return strings.Join(lines, "\n")
 }
 
+// ToModel converts the resolved social to its DB model
+func (social *Social) ToModel(artistID string, position int) SocialDB {
+	return SocialDB{
+		ArtistID:    artistID,
+		Position:    position,
+		SocialCode:  social.SocialCode,
+		Handle:      social.Username,
+		Link:        social.Link,
+		Description: social.Description,
+		IsSpecial:   social.IsSpecial,
+	}
+}
+
+// Marshal writes the resolved social as [*]link,description, quoting the fields
+// if needed
 func (social *Social) Marshal() (string, error) {
-	switch {
-	case social.Link != "" && social.Description != "" && social.IsSpecial:
-		return fmt.Sprintf("*%s,%s", social.Link, social.Description), nil
-	case social.Link != "" && social.Description != "":
-		return fmt.Sprintf("%s,%s", social.Link, social.Description), nil
-	default:
+	if social.Link == "" || social.Description == "" {
 		return "", fmt.Errorf("Social.Marshal: social link and description are empty")
 	}
+	line := quoteField(social.Link, ',') + "," + quoteField(social.Description, ',')
+	if social.IsSpecial {
+		line = "*" + line
+	}
+	return line, nil
+}
+
+// Marshal writes the social as [*]link,description, like Social.Marshal
+func (social *SocialDB) Marshal() (string, error) {
+	return (&Social{
+		Link:        social.Link,
+		Description: social.Description,
+		IsSpecial:   social.IsSpecial,
+	}).Marshal()
 }
@@ -0,0 +1,136 @@
+package artist
+
+import (
+	"artistdb-go/src/utils"
+	"reflect"
+	"testing"
+)
+
+func TestSocialMarshal(t *testing.T) {
+	tests := []struct {
+		social SocialDB
+		want   string
+	}{
+		{SocialDB{Link: "//x.com/paul", Description: "𝕏 | Life"}, "//x.com/paul,𝕏 | Life"},
+		{SocialDB{Link: "//example.com/shop", Description: `Prints, stickers & "zines"`, IsSpecial: true}, `*//example.com/shop,"Prints, stickers & ""zines"""`},
+		{SocialDB{Link: "//example.com/#about", Description: " padded "}, `//example.com/#about," padded "`},
+	}
+	for _, test := range tests {
+		t.Run(test.want, func(t *testing.T) {
+			got, err := test.social.Marshal()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("Marshal() = %q, want %q", got, test.want)
+			}
+		})
+	}
+
+	if _, err := (&SocialDB{Link: "//x.com/paul"}).Marshal(); err == nil {
+		t.Errorf("Marshal() of a social without description didn't fail")
+	}
+}
+
+func TestSocialUnmarshal(t *testing.T) {
+	appState := &utils.AppState{SupportedSocials: utils.NewSocialDBInstance()}
+	tests := []struct {
+		raw  string
+		want Social
+		// code of the diagnostic, empty if the line is valid
+		code Code
+	}{
+		{raw: "paul@x", want: Social{Username: "paul", SocialCode: "x", Link: "x.com/paul", Description: "𝕏"}},
+		{raw: "paul@x,Life", want: Social{Username: "paul", SocialCode: "x", Link: "x.com/paul", Description: "𝕏 | Life"}},
+		{raw: "*paul@instagram", want: Social{Username: "paul", SocialCode: "instagram", Link: "instagram.com/paul", Description: "Instagram", IsSpecial: true}},
+		{raw: "paul@linktr.ee", want: Social{Username: "paul", SocialCode: "linktr.ee", Link: "linktr.ee/paul", Description: "Linktr.ee 🌲", IsSpecial: true}},
+		{raw: "paul@twitter", want: Social{Username: "paul", SocialCode: "x", Link: "x.com/paul", Description: "𝕏"}},
+		{raw: "Paul@tumblr", want: Social{Username: "paul", SocialCode: "tumblr", Link: "paul.tumblr.com", Description: "Tumblr"}},
+		{raw: "paul@mastodon.art@mastodon,Art", want: Social{Username: "paul@mastodon.art", SocialCode: "mastodon", Link: "mastodon.art/@paul", Description: "Mastodon | Art"}},
+		{raw: "//example.com/paul,Website", want: Social{Link: "//example.com/paul", Description: "Website"}},
+		{raw: "//example.com/paul", code: CODE_LINK_NEEDS_DESC},
+		{raw: "paul@instagarm", code: CODE_UNKNOWN_SOCIAL},
+		{raw: "paul_art@tumblr", code: CODE_INVALID_HANDLE},
+		{raw: "paul@mastodon", code: CODE_INVALID_HANDLE},
+	}
+	for _, test := range tests {
+		t.Run(test.raw, func(t *testing.T) {
+			social := Social{}
+			diag := social.Unmarshal(appState, "paul", test.raw, Position{Line: 1, Column: 1})
+			if diag != nil || test.code != "" {
+				if diag == nil || diag.Code != test.code {
+					t.Fatalf("Unmarshal(%q) = %v, want %q", test.raw, diag, test.code)
+				}
+				return
+			}
+			got := Social{
+				Username:    social.Username,
+				SocialCode:  social.SocialCode,
+				Link:        social.Link,
+				Description: social.Description,
+				IsSpecial:   social.IsSpecial,
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Unmarshal(%q) = %+v, want %+v", test.raw, got, test.want)
+			}
+		})
+	}
+}
+
+func TestCheckProfileLink(t *testing.T) {
+	socials := utils.NewSocialDBInstance()
+	tests := []struct {
+		raw        string
+		suggestion string
+	}{
+		{"//www.instagram.com/paul/,Art", "use paul@instagram"},
+		{"https://twitter.com/paul,Art", "use paul@x"},
+		{"//example.com/paul,Website", ""},
+		{"paul@instagram", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.raw, func(t *testing.T) {
+			social := Social{}
+			if diag := social.Parse("paul", test.raw, Position{Line: 1, Column: 1}); diag != nil {
+				t.Fatal(diag)
+			}
+			diag := social.checkProfileLink(socials, "paul")
+			if test.suggestion == "" {
+				if diag != nil {
+					t.Errorf("checkProfileLink(%q) = %v, want nil", test.raw, diag)
+				}
+				return
+			}
+			if diag == nil || diag.Code != CODE_PROFILE_LINK || diag.Suggestion != test.suggestion {
+				t.Errorf("checkProfileLink(%q) = %v, want %s with %q", test.raw, diag, CODE_PROFILE_LINK, test.suggestion)
+			}
+		})
+	}
+}
+
+func TestCheckDeprecatedCode(t *testing.T) {
+	socials := utils.NewSocialDBInstance()
+	tests := []struct {
+		socialCode string
+		suggestion string
+	}{
+		{"twitter", "use x"},
+		{"x", ""},
+		{"fb", ""},
+		{"instagarm", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.socialCode, func(t *testing.T) {
+			diag := checkDeprecatedCode(socials, test.socialCode, Position{Line: 1, Column: 6})
+			if test.suggestion == "" {
+				if diag != nil {
+					t.Errorf("checkDeprecatedCode(%q) = %v, want nil", test.socialCode, diag)
+				}
+				return
+			}
+			if diag == nil || diag.Code != CODE_DEPRECATED_SOCIAL || diag.Suggestion != test.suggestion {
+				t.Errorf("checkDeprecatedCode(%q) = %v, want %s with %q", test.socialCode, diag, CODE_DEPRECATED_SOCIAL, test.suggestion)
+			}
+		})
+	}
+}
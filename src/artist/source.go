@@ -0,0 +1,188 @@
+package artist
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Source is the content of one artists file
+type Source struct {
+	Name    string
+	Content string
+}
+
+// ReadSources reads every artists file in paths
+func ReadSources(paths []string) ([]Source, error) {
+	sources := make([]Source, 0, len(paths))
+	for _, path := range paths {
+		rawBytes, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, Source{Name: path, Content: string(rawBytes)})
+	}
+	return sources, nil
+}
+
+// HashSources returns the sha256 of the artists files. For a single file it's
+// the hash of its content, so it can be compared with the output of sha256sum;
+// for several files it's the hash of the sha256sum lines of every file.
+func HashSources(sources []Source) string {
+	if len(sources) == 1 {
+		sum := sha256.Sum256([]byte(sources[0].Content))
+		return hex.EncodeToString(sum[:])
+	}
+	hasher := sha256.New()
+	for _, source := range sources {
+		sum := sha256.Sum256([]byte(source.Content))
+		fmt.Fprintf(hasher, "%s  %s\n", hex.EncodeToString(sum[:]), source.Name)
+	}
+	return hex.EncodeToString(hasher.Sum(nil))
+}
+
+// Block is one artist entry in the artists file, separated from the others by
+// at least one blank line
+type Block struct {
+	StartLine int
+	Raw       string
+}
+
+// SplitBlocks splits the artists file into blocks, keeping the line each block
+// starts at
+func SplitBlocks(text string) []Block {
+	blocks := make([]Block, 0)
+	current := make([]string, 0)
+	startLine := 0
+	flush := func() {
+		if len(current) > 0 {
+			blocks = append(blocks, Block{
+				StartLine: startLine,
+				Raw:       strings.Join(current, "\n"),
+			})
+		}
+		current = current[:0]
+	}
+
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		if len(current) == 0 {
+			startLine = i + 1
+		}
+		current = append(current, line)
+	}
+	flush()
+	return blocks
+}
+
+type field struct {
+	Value  string
+	Column int
+	// the field was wrapped in double quotes
+	Quoted bool
+	// the field opened a double quote without closing it
+	Unclosed bool
+}
+
+// splitFields splits a line on sep and trims the spaces around each field,
+// keeping the 1-based column each field starts at. A field wrapped in double
+// quotes can contain sep, # and spaces, a double quote inside it is written
+// twice, e.g. "Paul ""the Painter"", prints".
+func splitFields(line string, sep byte) []field {
+	fields := make([]field, 0)
+	i := 0
+	for {
+		for i < len(line) && (line[i] == ' ' || line[i] == '\t') {
+			i++
+		}
+		current := field{Column: i + 1}
+		var value strings.Builder
+		if i < len(line) && line[i] == '"' {
+			current.Quoted = true
+			current.Unclosed = true
+			for i++; i < len(line); i++ {
+				if line[i] != '"' {
+					value.WriteByte(line[i])
+					continue
+				}
+				if i+1 < len(line) && line[i+1] == '"' {
+					value.WriteByte('"')
+					i++
+					continue
+				}
+				current.Unclosed = false
+				i++
+				break
+			}
+		}
+		// anything after the closing quote is kept, minus the trailing spaces
+		start := i
+		for i < len(line) && line[i] != sep {
+			i++
+		}
+		value.WriteString(strings.TrimRight(line[start:i], " \t"))
+		current.Value = value.String()
+		fields = append(fields, current)
+		if i >= len(line) {
+			return fields
+		}
+		i++ // skip sep
+	}
+}
+
+// quoteField wraps value in double quotes if it can't be written as is in a
+// field split by sep
+func quoteField(value string, sep byte) string {
+	needsQuotes := strings.IndexByte(value, sep) >= 0 ||
+		strings.Contains(value, "\"") ||
+		strings.HasPrefix(value, "#") ||
+		strings.Contains(value, " #") ||
+		strings.Contains(value, "\t#") ||
+		strings.TrimSpace(value) != value
+	if !needsQuotes {
+		return value
+	}
+	return `"` + strings.ReplaceAll(value, `"`, `""`) + `"`
+}
+
+// splitComment splits a line into its content and its comment. A comment starts
+// with # at the beginning of the line or after a space, so links like
+// //example.com/#about are left alone. # inside double quotes is not a comment.
+func splitComment(line string) (string, string) {
+	inQuotes := false
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '"':
+			inQuotes = !inQuotes
+		case line[i] != '#' || inQuotes:
+		case i == 0 || line[i-1] == ' ' || line[i-1] == '\t':
+			return strings.TrimRight(line[:i], " \t"), strings.TrimRight(line[i:], " \t")
+		}
+	}
+	return line, ""
+}
+
+// commentAfterText returns the free-text field of line, the display name or
+// the description at index, if a trailing comment follows it directly. Such a
+// comment could have been meant as part of the field, e.g. paul,Paul #1.
+func commentAfterText(line, comment string, index int) (field, bool) {
+	if comment == "" {
+		return field{}, false
+	}
+	fields := splitFields(line, ',')
+	last := fields[len(fields)-1]
+	return last, len(fields)-1 == index && last.Value != "" && last.Value != "_" && !last.Quoted
+}
+
+func withComment(line, comment string) string {
+	if comment == "" {
+		return line
+	}
+	return line + " " + comment
+}
@@ -0,0 +1,141 @@
+package artist
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitFields(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want []field
+	}{
+		{
+			name: "plain",
+			line: "paul,Paul,paul@x",
+			want: []field{{Value: "paul", Column: 1}, {Value: "Paul", Column: 6}, {Value: "paul@x", Column: 11}},
+		},
+		{
+			name: "spaces around fields are trimmed",
+			line: " paul ,  Paul\t",
+			want: []field{{Value: "paul", Column: 2}, {Value: "Paul", Column: 10}},
+		},
+		{
+			name: "empty fields",
+			line: "paul,,",
+			want: []field{{Value: "paul", Column: 1}, {Value: "", Column: 6}, {Value: "", Column: 7}},
+		},
+		{
+			name: "quoted separator",
+			line: `paul,"Paul, the Painter"`,
+			want: []field{{Value: "paul", Column: 1}, {Value: "Paul, the Painter", Column: 6, Quoted: true}},
+		},
+		{
+			name: "doubled quote",
+			line: `"say ""hi"""`,
+			want: []field{{Value: `say "hi"`, Column: 1, Quoted: true}},
+		},
+		{
+			name: "quoted spaces are kept",
+			line: `"  paul  "`,
+			want: []field{{Value: "  paul  ", Column: 1, Quoted: true}},
+		},
+		{
+			name: "unclosed quote takes the rest of the line",
+			line: `paul,"Paul, the`,
+			want: []field{{Value: "paul", Column: 1}, {Value: "Paul, the", Column: 6, Quoted: true, Unclosed: true}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := splitFields(test.line, ','); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("splitFields(%q) = %+v, want %+v", test.line, got, test.want)
+			}
+		})
+	}
+}
+
+func TestQuoteFieldRoundTrip(t *testing.T) {
+	tests := []struct {
+		value  string
+		quoted string
+	}{
+		{"paul", "paul"},
+		{"", ""},
+		{"//example.com/#about", "//example.com/#about"},
+		{"Paul, the Painter", `"Paul, the Painter"`},
+		{`say "hi"`, `"say ""hi"""`},
+		{" paul", `" paul"`},
+		{"paul\t", "\"paul\t\""},
+		{"#1 fan", `"#1 fan"`},
+		{"fan #1", `"fan #1"`},
+		{`Prints, stickers & "zines"`, `"Prints, stickers & ""zines"""`},
+	}
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			quoted := quoteField(test.value, ',')
+			if quoted != test.quoted {
+				t.Errorf("quoteField(%q) = %q, want %q", test.value, quoted, test.quoted)
+			}
+
+			// the field must come back unchanged from a line with other fields
+			// and a comment
+			line := withComment("a,"+quoted+",b", "# comment")
+			content, _ := splitComment(line)
+			fields := splitFields(content, ',')
+			if len(fields) != 3 || fields[1].Value != test.value || fields[1].Unclosed {
+				t.Errorf("splitFields(%q) = %+v, want a,%q,b", content, fields, test.value)
+			}
+		})
+	}
+}
+
+func TestSplitComment(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		content string
+		comment string
+	}{
+		{"no comment", "paul@x,Life", "paul@x,Life", ""},
+		{"whole line", "# closed until March", "", "# closed until March"},
+		{"after a space", "paul@x,Life # verified", "paul@x,Life", "# verified"},
+		{"after a tab", "paul@x\t# verified", "paul@x", "# verified"},
+		{"trailing spaces are trimmed", "paul@x  # verified  ", "paul@x", "# verified"},
+		{"inside a link", "//example.com/#about,About", "//example.com/#about,About", ""},
+		{"inside quotes", `//example.com,"Shop #1" # main`, `//example.com,"Shop #1"`, "# main"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			content, comment := splitComment(test.line)
+			if content != test.content || comment != test.comment {
+				t.Errorf("splitComment(%q) = %q, %q, want %q, %q",
+					test.line, content, comment, test.content, test.comment)
+			}
+		})
+	}
+}
+
+func TestCommentAfterText(t *testing.T) {
+	tests := []struct {
+		line string
+		want bool
+	}{
+		{"paul,Paul #1", true},
+		{"paul@x,Life #1", true},
+		{`paul,"Paul #1"`, false},
+		{"paul #1", false},
+		{"paul,_ #1", false},
+		{"paul,Paul,paul@x #1", false},
+		{"paul,Paul", false},
+	}
+	for _, test := range tests {
+		t.Run(test.line, func(t *testing.T) {
+			line, comment := splitComment(test.line)
+			if _, got := commentAfterText(line, comment, 1); got != test.want {
+				t.Errorf("commentAfterText(%q, %q) = %v, want %v", line, comment, got, test.want)
+			}
+		})
+	}
+}
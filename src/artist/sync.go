@@ -0,0 +1,158 @@
+package artist
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/uptrace/bun"
+)
+
+// ChangeSet lists the usernames that differ between the parsed artists and the
+// ones in the database
+type ChangeSet struct {
+	Added    []string `json:"added"`
+	Removed  []string `json:"removed"`
+	Modified []string `json:"modified"`
+}
+
+func (changes ChangeSet) IsEmpty() bool {
+	return len(changes.Added) == 0 && len(changes.Removed) == 0 && len(changes.Modified) == 0
+}
+
+func (changes ChangeSet) Log() {
+	if changes.IsEmpty() {
+		slog.Info("no artist changed")
+		return
+	}
+	slog.Info("artists changed",
+		"added", changes.Added,
+		"removed", changes.Removed,
+		"modified", changes.Modified)
+}
+
+// Diff compares the hashes of the parsed artists with the ones in the database
+func Diff(ctx context.Context, db bun.IDB, artists []ArtistDB) (ChangeSet, error) {
+	existing := make([]ArtistDB, 0)
+	if err := db.NewSelect().
+		Model(&existing).
+		Column("id", "hash").
+		Scan(ctx); err != nil {
+		return ChangeSet{}, err
+	}
+	existingHashes := make(map[string]string, len(existing))
+	for _, artist := range existing {
+		existingHashes[artist.ID] = artist.Hash
+	}
+
+	changes := ChangeSet{
+		Added:    make([]string, 0),
+		Removed:  make([]string, 0),
+		Modified: make([]string, 0),
+	}
+	for _, artist := range artists {
+		hash, ok := existingHashes[artist.ID]
+		switch {
+		case !ok:
+			changes.Added = append(changes.Added, artist.ID)
+		case hash != artist.Hash:
+			changes.Modified = append(changes.Modified, artist.ID)
+		}
+		delete(existingHashes, artist.ID)
+	}
+	for _, artist := range existing {
+		if _, ok := existingHashes[artist.ID]; ok {
+			changes.Removed = append(changes.Removed, artist.ID)
+		}
+	}
+	return changes, nil
+}
+
+// Sync writes only the added, modified and removed artists, their socials and
+// aliases to the database, in one transaction
+func Sync(ctx context.Context, db *bun.DB, artists []ArtistDB) (ChangeSet, error) {
+	var changes ChangeSet
+	err := db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		var err error
+		changes, err = Diff(ctx, tx, artists)
+		if err != nil || changes.IsEmpty() {
+			return err
+		}
+
+		// aliases & socials of removed & modified artists are deleted first,
+		// aliases may have moved to another artist
+		staleIDs := append(append([]string{}, changes.Removed...), changes.Modified...)
+		if len(staleIDs) > 0 {
+			for _, model := range []any{(*AliasDB)(nil), (*SocialDB)(nil)} {
+				if _, err := tx.NewDelete().
+					Model(model).
+					Where("artist_id IN (?)", bun.In(staleIDs)).
+					Exec(ctx); err != nil {
+					return err
+				}
+			}
+		}
+		if len(changes.Removed) > 0 {
+			if _, err := tx.NewDelete().
+				Model((*ArtistDB)(nil)).
+				Where("id IN (?)", bun.In(changes.Removed)).
+				Exec(ctx); err != nil {
+				return err
+			}
+		}
+
+		added := make(map[string]struct{}, len(changes.Added))
+		for _, id := range changes.Added {
+			added[id] = struct{}{}
+		}
+		modified := make(map[string]struct{}, len(changes.Modified))
+		for _, id := range changes.Modified {
+			modified[id] = struct{}{}
+		}
+		artistsToInsert := make([]ArtistDB, 0, len(changes.Added))
+		aliasesToInsert := make([]AliasDB, 0)
+		socialsToInsert := make([]SocialDB, 0)
+		for _, artist := range artists {
+			_, isAdded := added[artist.ID]
+			_, isModified := modified[artist.ID]
+			switch {
+			case isAdded:
+				artistsToInsert = append(artistsToInsert, artist)
+			case isModified:
+				if _, err := tx.NewUpdate().
+					Model(&artist).
+					WherePK().
+					Exec(ctx); err != nil {
+					return err
+				}
+			default:
+				continue
+			}
+			aliasesToInsert = append(aliasesToInsert, artist.AliasModels()...)
+			socialsToInsert = append(socialsToInsert, artist.Socials...)
+		}
+
+		if len(artistsToInsert) > 0 {
+			if _, err := tx.NewInsert().
+				Model(&artistsToInsert).
+				Exec(ctx); err != nil {
+				return err
+			}
+		}
+		if len(aliasesToInsert) > 0 {
+			if _, err := tx.NewInsert().
+				Model(&aliasesToInsert).
+				Exec(ctx); err != nil {
+				return err
+			}
+		}
+		if len(socialsToInsert) > 0 {
+			if _, err := tx.NewInsert().
+				Model(&socialsToInsert).
+				Exec(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return changes, err
+}
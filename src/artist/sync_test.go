@@ -0,0 +1,135 @@
+package artist
+
+import (
+	"artistdb-go/src/utils"
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestSync(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	appState := &utils.AppState{SupportedSocials: utils.NewSocialDBInstance()}
+
+	// each step syncs its file over the database left by the previous one
+	tests := []struct {
+		name    string
+		file    string
+		want    ChangeSet
+		aliases map[string]string
+		socials map[string]int
+	}{
+		{
+			name:    "first load adds everything",
+			file:    "paul,Paul,_,painter\npaul@x\n\nzed\nzed@instagram\nzed@x\n",
+			want:    ChangeSet{Added: []string{"paul", "zed"}, Removed: []string{}, Modified: []string{}},
+			aliases: map[string]string{"paul": "paul", "painter": "paul", "zed": "zed"},
+			socials: map[string]int{"paul": 1, "zed": 2},
+		},
+		{
+			name:    "same artists in another order change nothing",
+			file:    "zed\nzed@instagram\nzed@x\n\npaul,Paul,_,painter\npaul@x\n",
+			want:    ChangeSet{Added: []string{}, Removed: []string{}, Modified: []string{}},
+			aliases: map[string]string{"paul": "paul", "painter": "paul", "zed": "zed"},
+			socials: map[string]int{"paul": 1, "zed": 2},
+		},
+		{
+			name:    "alias moves to a modified artist",
+			file:    "paul,Paul\npaul@x\n\nzed,Zed,_,painter\nzed@instagram\n",
+			want:    ChangeSet{Added: []string{}, Removed: []string{}, Modified: []string{"paul", "zed"}},
+			aliases: map[string]string{"paul": "paul", "painter": "zed", "zed": "zed"},
+			socials: map[string]int{"paul": 1, "zed": 1},
+		},
+		{
+			name:    "removed and added",
+			file:    "amy\namy@x\n\nzed,Zed,_,painter\nzed@instagram\n",
+			want:    ChangeSet{Added: []string{"amy"}, Removed: []string{"paul"}, Modified: []string{}},
+			aliases: map[string]string{"amy": "amy", "painter": "zed", "zed": "zed"},
+			socials: map[string]int{"amy": 1, "zed": 1},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			artists, parsed := ParseSources(appState, []Source{{Name: "artists.txt", Content: test.file}})
+			if parsed.Diagnostics.HasErrors() {
+				t.Fatalf("parse errors: %v", parsed.Diagnostics)
+			}
+			changes, err := Sync(ctx, db, artists)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(changes, test.want) {
+				t.Errorf("Sync() = %+v, want %+v", changes, test.want)
+			}
+
+			aliasRows := make([]AliasDB, 0)
+			if err := db.NewSelect().Model(&aliasRows).Scan(ctx); err != nil {
+				t.Fatal(err)
+			}
+			aliases := make(map[string]string, len(aliasRows))
+			for _, alias := range aliasRows {
+				aliases[alias.Alias] = alias.ID
+			}
+			if !reflect.DeepEqual(aliases, test.aliases) {
+				t.Errorf("aliases = %v, want %v", aliases, test.aliases)
+			}
+
+			socialRows := make([]SocialDB, 0)
+			if err := db.NewSelect().Model(&socialRows).Scan(ctx); err != nil {
+				t.Fatal(err)
+			}
+			socials := make(map[string]int)
+			for _, social := range socialRows {
+				socials[social.ArtistID]++
+			}
+			if !reflect.DeepEqual(socials, test.socials) {
+				t.Errorf("socials per artist = %v, want %v", socials, test.socials)
+			}
+
+			// the stored artists are the parsed ones
+			stored := make([]ArtistDB, 0)
+			if err := db.NewSelect().Model(&stored).Scan(ctx); err != nil {
+				t.Fatal(err)
+			}
+			storedHashes := make(map[string]string, len(stored))
+			for _, artist := range stored {
+				storedHashes[artist.ID] = artist.Hash
+			}
+			parsedHashes := make(map[string]string, len(artists))
+			for _, artist := range artists {
+				parsedHashes[artist.ID] = artist.Hash
+			}
+			if !reflect.DeepEqual(storedHashes, parsedHashes) {
+				t.Errorf("stored hashes = %v, want %v", storedHashes, parsedHashes)
+			}
+		})
+	}
+}
+
+func TestForeignKeys(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	if _, err := db.NewInsert().Model(&AliasDB{Alias: "painter", ID: "paul"}).Exec(ctx); err == nil {
+		t.Fatal("inserted an alias of an artist that doesn't exist")
+	}
+
+	if _, err := db.NewInsert().Model(&ArtistDB{ID: "paul"}).Exec(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.NewInsert().Model(&AliasDB{Alias: "painter", ID: "paul"}).Exec(ctx); err != nil {
+		t.Fatal(err)
+	}
+	social := SocialDB{ArtistID: "paul", Link: "//x.com/paul", Description: "𝕏"}
+	if _, err := db.NewInsert().Model(&social).Exec(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.NewDelete().Model((*ArtistDB)(nil)).Where("id = ?", "paul").Exec(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for _, model := range []any{(*AliasDB)(nil), (*SocialDB)(nil)} {
+		if count, err := db.NewSelect().Model(model).Count(ctx); err != nil || count != 0 {
+			t.Errorf("%d rows of %T left after deleting their artist, %v", count, model, err)
+		}
+	}
+}
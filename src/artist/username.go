@@ -0,0 +1,57 @@
+package artist
+
+import (
+	"artistdb-go/src/utils"
+	"fmt"
+	"slices"
+	"unicode"
+)
+
+// checkURLSafe reports a username or alias that can't be used as is in the
+// path of an artist page. Letters and digits of any script are allowed, along
+// with - . _ and ~, the other characters RFC 3986 leaves unreserved.
+func checkURLSafe(name string, pos Position) *Diagnostic {
+	if name == "." || name == ".." {
+		diag := NewDiagnostic(CODE_UNSAFE_USERNAME, pos, name)
+		return &diag
+	}
+	for _, r := range name {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), unicode.IsMark(r):
+		case r == '-', r == '.', r == '_', r == '~':
+		default:
+			diag := NewDiagnostic(CODE_UNSAFE_USERNAME, pos, name)
+			diag.Suggestion = fmt.Sprintf("remove %q", r)
+			return &diag
+		}
+	}
+	return nil
+}
+
+// checkReserved reports a username or alias that a route of the server would
+// shadow
+func checkReserved(reserved []string, name string, pos Position) *Diagnostic {
+	if !slices.Contains(reserved, name) {
+		return nil
+	}
+	diag := NewDiagnostic(CODE_RESERVED_USERNAME, pos, name)
+	return &diag
+}
+
+// checkConfusable warns about a username or alias that would look identical to
+// a different one seen earlier in the parse, e.g. with a Cyrillic а
+func (state *ParseState) checkConfusable(name string, pos Position) *Diagnostic {
+	skeleton := utils.Skeleton(name)
+	other, ok := state.skeletons[skeleton]
+	if !ok {
+		state.skeletons[skeleton] = name
+		state.journal = append(state.journal, func() { delete(state.skeletons, skeleton) })
+		return nil
+	}
+	if other == name {
+		return nil
+	}
+	diag := NewDiagnostic(CODE_CONFUSABLE_USERNAME, pos, name)
+	diag.Suggestion = fmt.Sprintf("it looks like %q, rename one of them", other)
+	return &diag
+}
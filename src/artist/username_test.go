@@ -0,0 +1,90 @@
+package artist
+
+import "testing"
+
+func TestCheckURLSafe(t *testing.T) {
+	tests := []struct {
+		name       string
+		want       bool
+		suggestion string
+	}{
+		{"paul", true, ""},
+		{"paul.art", true, ""},
+		{"paul-art_2~", true, ""},
+		{"café", true, ""},
+		{"ポール", true, ""},
+		{"пол", true, ""},
+		{".", false, ""},
+		{"..", false, ""},
+		{"paul art", false, `remove ' '`},
+		{"paul/art", false, `remove '/'`},
+		{"paul?", false, `remove '?'`},
+		{"paul%20", false, `remove '%'`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			diag := checkURLSafe(test.name, Position{Line: 1, Column: 1})
+			if got := diag == nil; got != test.want {
+				t.Fatalf("checkURLSafe(%q) = %v, want safe %v", test.name, diag, test.want)
+			}
+			if diag == nil {
+				return
+			}
+			if diag.Code != CODE_UNSAFE_USERNAME || diag.Suggestion != test.suggestion {
+				t.Errorf("checkURLSafe(%q) = %s, %q, want %s, %q",
+					test.name, diag.Code, diag.Suggestion, CODE_UNSAFE_USERNAME, test.suggestion)
+			}
+		})
+	}
+}
+
+func TestCheckReserved(t *testing.T) {
+	reserved := []string{"admin", "api", "favicon.ico"}
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"paul", false},
+		{"admin", true},
+		{"favicon.ico", true},
+		{"apis", false},
+		{"", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			diag := checkReserved(reserved, test.name, Position{Line: 1, Column: 1})
+			if got := diag != nil; got != test.want {
+				t.Errorf("checkReserved(%q) = %v, want reserved %v", test.name, diag, test.want)
+			}
+			if diag != nil && diag.Code != CODE_RESERVED_USERNAME {
+				t.Errorf("checkReserved(%q) = %s, want %s", test.name, diag.Code, CODE_RESERVED_USERNAME)
+			}
+		})
+	}
+}
+
+func TestCheckConfusable(t *testing.T) {
+	// the names are checked in order against the same parse
+	state := NewParseState()
+	tests := []struct {
+		name      string
+		confusing bool
+	}{
+		{"paul", false},
+		{"amy", false},
+		{"pаul", true}, // Cyrillic а
+		{"paul", false},
+		{"pau1", true},
+		{"аmy", true},
+		{"paula", false},
+	}
+	for _, test := range tests {
+		diag := state.checkConfusable(test.name, Position{Line: 1, Column: 1})
+		if got := diag != nil; got != test.confusing {
+			t.Errorf("checkConfusable(%q) = %v, want confusing %v", test.name, diag, test.confusing)
+		}
+		if diag != nil && diag.Code != CODE_CONFUSABLE_USERNAME {
+			t.Errorf("checkConfusable(%q) = %s, want %s", test.name, diag.Code, CODE_CONFUSABLE_USERNAME)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"artistdb-go/src/artist"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+)
+
+// Codes prints the catalog of diagnostic codes, as a table or as JSON with
+// -json
+func Codes(args []string) int {
+	flagSet := flag.NewFlagSet("codes", flag.ExitOnError)
+	asJSON := flagSet.Bool("json", false, "print the catalog as JSON")
+	flagSet.Usage = func() {
+		fmt.Fprintln(flagSet.Output(), "usage: artistdb-go codes [-json]")
+		flagSet.PrintDefaults()
+	}
+	flagSet.Parse(args)
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(artist.Catalog); err != nil {
+			slog.Error(err.Error())
+			return 1
+		}
+		return 0
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "CODE\tSEVERITY\tTITLE\tHELP")
+	for _, info := range artist.Catalog {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", info.Code, info.Severity, info.Title, info.Help)
+	}
+	if err := writer.Flush(); err != nil {
+		slog.Error(err.Error())
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"artistdb-go/src/artist"
+	"artistdb-go/src/utils"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// Format rewrites the artists file, or every artists file in a directory, in
+// the canonical layout. With -check the file is left untouched and the exit
+// code is 1 if it isn't formatted, for use in pre-commit hooks. A file with
+// errors is never rewritten and exits with code 1 too. With -rewrite-links the
+// custom links to the profile of a supported social are rewritten into
+// username@socialcode.
+func Format(args []string) int {
+	flagSet := flag.NewFlagSet("fmt", flag.ExitOnError)
+	check := flagSet.Bool("check", false, "exit with code 1 if the file isn't formatted, without rewriting it")
+	rewriteLinks := flagSet.Bool("rewrite-links", false, "rewrite custom links to the profile of a supported social into username@socialcode")
+	flagSet.Usage = func() {
+		fmt.Fprintln(flagSet.Output(), "usage: artistdb-go fmt [-check] [-rewrite-links] [file or directory]")
+		flagSet.PrintDefaults()
+	}
+	flagSet.Parse(args)
+
+	inFile := os.Getenv("IN_FILE")
+	if flagSet.NArg() > 0 {
+		inFile = flagSet.Arg(0)
+	}
+	if inFile == "" {
+		inFile = "artists.txt"
+	}
+	inFiles, err := utils.ListInFiles(inFile)
+	if err != nil {
+		slog.Error(err.Error())
+		return 1
+	}
+
+	options := artist.FormatOptions{
+		RewriteLinks:     *rewriteLinks,
+		SupportedSocials: utils.NewParseAppState().SupportedSocials,
+	}
+
+	exitCode := 0
+	for _, inFile := range inFiles {
+		if code := formatFile(inFile, *check, options); code != 0 {
+			exitCode = code
+		}
+	}
+	return exitCode
+}
+
+func formatFile(inFile string, check bool, options artist.FormatOptions) int {
+	rawBytes, err := os.ReadFile(inFile)
+	if err != nil {
+		slog.Error(err.Error())
+		return 1
+	}
+
+	formatted, diags := artist.Format(string(rawBytes), options)
+	diags.InFile(inFile)
+	diags.Log()
+	// like gofmt, a file with errors is left untouched, the formatted layout
+	// could change what it means
+	if diags.HasErrors() {
+		slog.Error("not formatted, the file contains errors", "file", inFile)
+		return 1
+	}
+	if formatted == string(rawBytes) {
+		slog.Info("already formatted", "file", inFile)
+		return 0
+	}
+	if check {
+		slog.Error("not formatted, run artistdb-go fmt to fix", "file", inFile)
+		return 1
+	}
+
+	fileStat, err := os.Stat(inFile)
+	if err != nil {
+		slog.Error(err.Error())
+		return 1
+	}
+	if err := os.WriteFile(inFile, []byte(formatted), fileStat.Mode()); err != nil {
+		slog.Error(err.Error())
+		return 1
+	}
+	slog.Info("formatted", "file", inFile)
+	return 0
+}
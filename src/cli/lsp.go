@@ -0,0 +1,32 @@
+package cli
+
+import (
+	"artistdb-go/src/lsp"
+	"artistdb-go/src/routes"
+	"artistdb-go/src/utils"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// LSP runs the language server for the artists files over stdin and stdout,
+// logging to stderr
+func LSP(args []string) int {
+	flagSet := flag.NewFlagSet("lsp", flag.ExitOnError)
+	flagSet.Usage = func() {
+		fmt.Fprintln(flagSet.Output(), "usage: artistdb-go lsp")
+		flagSet.PrintDefaults()
+	}
+	flagSet.Parse(args)
+
+	// PARSE_MODE is ignored, lenient mode would hide the errors of the skipped
+	// artists
+	appState := utils.NewStrictParseAppState()
+	appState.ReservedSegments = routes.ReservedSegments()
+	if err := lsp.Serve(appState, os.Stdin, os.Stdout); err != nil {
+		slog.Error(err.Error())
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"artistdb-go/src/artist"
+	"artistdb-go/src/routes"
+	"artistdb-go/src/utils"
+	"artistdb-go/src/validate"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/sqlitedialect"
+	"github.com/uptrace/bun/driver/sqliteshim"
+)
+
+// Validate parses the artists file, every artists file in a directory, or stdin
+// with -, the same way a reload would, and prints the result as JSON. The live
+// database is only read to list the artists that would change. The exit code
+// is 1 if the artists contain errors.
+func Validate(args []string) int {
+	flagSet := flag.NewFlagSet("validate", flag.ExitOnError)
+	dbPath := flagSet.String("db", "", "live database to compare with, defaults to SQLITE or ./sqlite.db, skipped if it doesn't exist")
+	flagSet.Usage = func() {
+		fmt.Fprintln(flagSet.Output(), "usage: artistdb-go validate [-db path] [file, directory or -]")
+		flagSet.PrintDefaults()
+	}
+	flagSet.Parse(args)
+
+	inFile := os.Getenv("IN_FILE")
+	if flagSet.NArg() > 0 {
+		inFile = flagSet.Arg(0)
+	}
+	if inFile == "" {
+		inFile = "artists.txt"
+	}
+	sources, err := readValidateSources(inFile)
+	if err != nil {
+		slog.Error(err.Error())
+		return 1
+	}
+
+	ctx := context.Background()
+	live, err := openLiveDB(*dbPath)
+	if err != nil {
+		slog.Error("can't open live database", "err", err)
+		return 1
+	}
+	// a nil *bun.DB would make a non-nil bun.IDB
+	var liveIDB bun.IDB
+	if live != nil {
+		defer live.Close()
+		liveIDB = live
+	}
+
+	appState := utils.NewParseAppState()
+	appState.ReservedSegments = routes.ReservedSegments()
+	result, err := validate.Sources(ctx, appState, liveIDB, sources)
+	if err != nil {
+		slog.Error("failed to validate artists", "err", err)
+		return 1
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(result); err != nil {
+		slog.Error(err.Error())
+		return 1
+	}
+	if !result.Valid {
+		return 1
+	}
+	return 0
+}
+
+func readValidateSources(inFile string) ([]artist.Source, error) {
+	if inFile == "-" {
+		rawBytes, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, err
+		}
+		return []artist.Source{{Content: string(rawBytes)}}, nil
+	}
+	inFiles, err := utils.ListInFiles(inFile)
+	if err != nil {
+		return nil, err
+	}
+	return artist.ReadSources(inFiles)
+}
+
+// openLiveDB opens the live database read-only, or returns nil if it doesn't
+// exist
+func openLiveDB(dbPath string) (*bun.DB, error) {
+	if dbPath == "" {
+		dbPath = os.Getenv("SQLITE")
+	}
+	dbPath = utils.SQLitePath(dbPath)
+	if _, err := os.Stat(dbPath); err != nil {
+		slog.Warn("live database not found, every artist is reported as added", "db", dbPath)
+		return nil, nil
+	}
+	sqldb, err := sql.Open(sqliteshim.ShimName, "file:"+dbPath+"?mode=ro")
+	if err != nil {
+		return nil, err
+	}
+	return bun.NewDB(sqldb, sqlitedialect.New()), nil
+}
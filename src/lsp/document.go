@@ -0,0 +1,133 @@
+package lsp
+
+import (
+	"artistdb-go/src/artist"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// document is an open artists file, parsed without resolving anything
+type document struct {
+	uri   string
+	text  string
+	lines []string
+	// one per block, with the 1-based lines the block spans
+	artists []documentArtist
+}
+
+type documentArtist struct {
+	artist.Artist
+	startLine int
+	endLine   int
+}
+
+func newDocument(uri, text string) *document {
+	doc := &document{
+		uri:     uri,
+		text:    text,
+		lines:   strings.Split(text, "\n"),
+		artists: make([]documentArtist, 0),
+	}
+	for _, block := range artist.SplitBlocks(text) {
+		parsed := documentArtist{
+			startLine: block.StartLine,
+			endLine:   block.StartLine + strings.Count(block.Raw, "\n"),
+		}
+		parsed.Parse(block.Raw, block.StartLine)
+		if parsed.Username == "" {
+			// a block made only of comments
+			continue
+		}
+		doc.artists = append(doc.artists, parsed)
+	}
+	return doc
+}
+
+// line returns the 1-based line, without the line ending
+func (doc *document) line(line int) string {
+	if line < 1 || line > len(doc.lines) {
+		return ""
+	}
+	return strings.TrimRight(doc.lines[line-1], "\r")
+}
+
+// artistAt returns the artist whose block spans the 1-based line
+func (doc *document) artistAt(line int) *documentArtist {
+	for i := range doc.artists {
+		if doc.artists[i].startLine <= line && line <= doc.artists[i].endLine {
+			return &doc.artists[i]
+		}
+	}
+	return nil
+}
+
+// toLSP converts a 1-based line and byte column to an LSP position
+func (doc *document) toLSP(pos artist.Position) Position {
+	line := doc.line(pos.Line)
+	column := min(max(pos.Column-1, 0), len(line))
+	return Position{
+		Line:      max(pos.Line-1, 0),
+		Character: len(utf16.Encode([]rune(line[:column]))),
+	}
+}
+
+// fromLSP converts an LSP position to a 1-based line and byte column
+func (doc *document) fromLSP(pos Position) artist.Position {
+	line := doc.line(pos.Line + 1)
+	units := 0
+	column := 0
+	for column < len(line) && units < pos.Character {
+		r, size := utf8.DecodeRuneInString(line[column:])
+		units += len(utf16.Encode([]rune{r}))
+		column += size
+	}
+	return artist.Position{Line: pos.Line + 1, Column: column + 1}
+}
+
+// fieldRange returns the range of the comma separated field starting at pos,
+// or the rest of the line if there's nothing there
+func (doc *document) fieldRange(pos artist.Position) Range {
+	line := doc.line(pos.Line)
+	end := min(max(pos.Column-1, 0), len(line))
+	for end < len(line) && line[end] != ',' {
+		end++
+	}
+	for end > pos.Column-1 && (line[end-1] == ' ' || line[end-1] == '\t') {
+		end--
+	}
+	if end <= pos.Column-1 {
+		end = len(line)
+	}
+	return Range{
+		Start: doc.toLSP(pos),
+		End:   doc.toLSP(artist.Position{Line: pos.Line, Column: end + 1}),
+	}
+}
+
+// fieldAt returns the comma separated field around pos without its quotes and
+// spaces, and where it starts
+func (doc *document) fieldAt(pos artist.Position) (string, artist.Position) {
+	line := doc.line(pos.Line)
+	column := min(max(pos.Column-1, 0), len(line))
+	start := strings.LastIndexByte(line[:column], ',') + 1
+	end := strings.IndexByte(line[column:], ',')
+	if end < 0 {
+		end = len(line)
+	} else {
+		end += column
+	}
+	value := line[start:end]
+	trimmed := strings.TrimLeft(value, " \t*")
+	start += len(value) - len(trimmed)
+	return strings.Trim(strings.TrimSpace(trimmed), `"`), artist.Position{Line: pos.Line, Column: start + 1}
+}
+
+// wholeRange spans the whole document
+func (doc *document) wholeRange() Range {
+	lastLine := len(doc.lines)
+	return Range{
+		Start: Position{},
+		End:   doc.toLSP(artist.Position{Line: lastLine, Column: len(doc.line(lastLine)) + 1}),
+	}
+}
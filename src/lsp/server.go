@@ -0,0 +1,349 @@
+package lsp
+
+import (
+	"artistdb-go/src/artist"
+	"artistdb-go/src/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Server is a language server for the artists files. It reuses the parser for
+// the diagnostics, the supported socials for completion and hover, and the
+// canonical formatter. Requests are handled one at a time.
+type Server struct {
+	appState  *utils.AppState
+	conn      *conn
+	documents map[string]*document
+	shutdown  bool
+}
+
+// Serve runs the language server over in and out, usually stdin and stdout,
+// until the client sends exit or closes in
+func Serve(appState *utils.AppState, in io.Reader, out io.Writer) error {
+	server := &Server{
+		appState:  appState,
+		conn:      newConn(in, out),
+		documents: make(map[string]*document),
+	}
+	for {
+		msg, err := server.conn.read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			var rpcErr *responseError
+			if errors.As(err, &rpcErr) {
+				slog.Warn("can't parse message", "err", err)
+				continue
+			}
+			return err
+		}
+		if msg.Method == "exit" {
+			if !server.shutdown {
+				return errors.New("Serve: exit without shutdown")
+			}
+			return nil
+		}
+
+		result, err := server.handle(msg)
+		if msg.ID == nil {
+			// notifications get no response
+			if err != nil {
+				slog.Warn("can't handle notification", "method", msg.Method, "err", err)
+			}
+			continue
+		}
+		if err := server.conn.reply(msg.ID, result, err); err != nil {
+			return err
+		}
+	}
+}
+
+func (server *Server) handle(msg *message) (any, error) {
+	switch msg.Method {
+	case "initialize":
+		return map[string]any{
+			"capabilities": map[string]any{
+				// full text on every change
+				"textDocumentSync": 1,
+				"completionProvider": map[string]any{
+					"triggerCharacters": []string{"@"},
+				},
+				"hoverProvider":              true,
+				"definitionProvider":         true,
+				"documentFormattingProvider": true,
+			},
+			"serverInfo": map[string]any{"name": "artistdb-go"},
+		}, nil
+	case "initialized", "$/cancelRequest", "$/setTrace":
+		return nil, nil
+	case "shutdown":
+		server.shutdown = true
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		server.open(params.TextDocument.URI, params.TextDocument.Text)
+		return nil, nil
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		if len(params.ContentChanges) == 0 {
+			return nil, nil
+		}
+		server.open(params.TextDocument.URI, params.ContentChanges[len(params.ContentChanges)-1].Text)
+		return nil, nil
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		delete(server.documents, params.TextDocument.URI)
+		return nil, server.conn.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+			URI:         params.TextDocument.URI,
+			Diagnostics: make([]Diagnostic, 0),
+		})
+
+	case "textDocument/completion":
+		var params TextDocumentPositionParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		doc, err := server.document(params.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		return server.completion(doc, doc.fromLSP(params.Position)), nil
+	case "textDocument/hover":
+		var params TextDocumentPositionParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		doc, err := server.document(params.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		return server.hover(doc, doc.fromLSP(params.Position)), nil
+	case "textDocument/definition":
+		var params TextDocumentPositionParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		doc, err := server.document(params.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		return server.definition(doc, doc.fromLSP(params.Position)), nil
+	case "textDocument/formatting":
+		var params DocumentFormattingParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		doc, err := server.document(params.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		return server.formatting(doc), nil
+
+	default:
+		return nil, &responseError{METHOD_NOT_FOUND, "method not supported: " + msg.Method}
+	}
+}
+
+func unmarshalParams(msg *message, params any) error {
+	if err := json.Unmarshal(msg.Params, params); err != nil {
+		return &responseError{INVALID_PARAMS, err.Error()}
+	}
+	return nil
+}
+
+func (server *Server) document(uri string) (*document, error) {
+	doc, ok := server.documents[uri]
+	if !ok {
+		return nil, &responseError{INVALID_PARAMS, "document not open: " + uri}
+	}
+	return doc, nil
+}
+
+// open stores the new text of the document and publishes its diagnostics
+func (server *Server) open(uri, text string) {
+	doc := newDocument(uri, text)
+	server.documents[uri] = doc
+
+	_, result := artist.ParseSources(server.appState, []artist.Source{{Content: text}})
+	diagnostics := make([]Diagnostic, 0, len(result.Diagnostics))
+	for _, diag := range result.Diagnostics {
+		severity := DIAGNOSTIC_WARNING
+		if diag.Severity == artist.SeverityError {
+			severity = DIAGNOSTIC_ERROR
+		}
+		message := diag.Message
+		if diag.Suggestion != "" {
+			message += ", " + diag.Suggestion
+		}
+		diagnostics = append(diagnostics, Diagnostic{
+			Range:    doc.fieldRange(diag.Position),
+			Severity: severity,
+			Code:     string(diag.Code),
+			Source:   "artistdb",
+			Message:  message,
+		})
+	}
+	if err := server.conn.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+		URI:         uri,
+		Diagnostics: diagnostics,
+	}); err != nil {
+		slog.Error("can't publish diagnostics", "uri", uri, "err", err)
+	}
+}
+
+// completion suggests the social codes after the @ of a username@socialcode
+func (server *Server) completion(doc *document, pos artist.Position) []CompletionItem {
+	line := doc.line(pos.Line)
+	beforeCursor := line[:min(max(pos.Column-1, 0), len(line))]
+	field := beforeCursor[strings.LastIndexByte(beforeCursor, ',')+1:]
+	at := strings.LastIndexByte(field, '@')
+	if at < 0 {
+		return make([]CompletionItem, 0)
+	}
+	prefix := strings.ToLower(field[at+1:])
+
+	items := make([]CompletionItem, 0)
+	for _, code := range server.appState.SupportedSocials.Codes() {
+		if !strings.HasPrefix(code, prefix) {
+			continue
+		}
+		items = append(items, CompletionItem{
+			Label:  code,
+			Kind:   COMPLETION_KIND_VALUE,
+			Detail: server.appState.SupportedSocials.DisplayName(code),
+		})
+	}
+	return items
+}
+
+// hover shows the profile and avatar URLs of the social or the avatar under
+// the cursor
+func (server *Server) hover(doc *document, pos artist.Position) *Hover {
+	parsed := doc.artistAt(pos.Line)
+	if parsed == nil {
+		return nil
+	}
+	socials := server.appState.SupportedSocials
+
+	// avatar
+	avatarPos := parsed.AvatarPosition()
+	if pos.Line == avatarPos.Line && pos.Column >= avatarPos.Column &&
+		(len(parsed.AliasPositions()) == 0 || pos.Column < parsed.AliasPositions()[0].Column) {
+		var contents string
+		at := strings.LastIndexByte(parsed.Avatar, '@')
+		switch {
+		case at >= 0:
+			handle, socialCode := parsed.Avatar[:at], parsed.Avatar[at+1:]
+			avatar, err := avatarURL(socials, handle, socialCode)
+			if err != nil {
+				contents = err.Error()
+				break
+			}
+			contents = fmt.Sprintf("Avatar: https:%s", avatar)
+		case parsed.Avatar == "_":
+			contents = "Avatar inferred from the first social with avatars"
+			for _, social := range parsed.Socials {
+				if avatar, err := avatarURL(socials, social.Username, social.SocialCode); err == nil {
+					contents = fmt.Sprintf("Avatar: https:%s\n\nInferred from `%s@%s`",
+						avatar, social.Username, social.SocialCode)
+					break
+				}
+			}
+		case strings.HasPrefix(parsed.Avatar, "/"):
+			contents = fmt.Sprintf("Avatar: `/avatar%s`, served from AVATAR_DIR", parsed.Avatar)
+		default:
+			return nil
+		}
+		avatarRange := doc.fieldRange(avatarPos)
+		return &Hover{MarkupContent{"markdown", contents}, &avatarRange}
+	}
+
+	// social
+	for _, social := range parsed.Socials {
+		if social.Position.Line != pos.Line || social.SocialCode == "" {
+			continue
+		}
+		lines := make([]string, 0, 3)
+		if displayName := socials.DisplayName(social.SocialCode); displayName != "" {
+			lines = append(lines, fmt.Sprintf("**%s** (`%s`)", displayName, social.SocialCode))
+		}
+		if diag := social.Resolve(server.appState, parsed.Username); diag != nil {
+			lines = append(lines, fmt.Sprintf("%s: %s", diag.Code, diag.Message))
+		} else {
+			lines = append(lines, "Profile: https://"+social.Link)
+		}
+		if avatar, err := avatarURL(socials, social.Username, social.SocialCode); err == nil {
+			lines = append(lines, "Avatar: https:"+avatar)
+		}
+		return &Hover{Contents: MarkupContent{"markdown", strings.Join(lines, "\n\n")}}
+	}
+	return nil
+}
+
+// avatarURL returns the avatar link of handle@socialCode, like the parser does
+func avatarURL(socials *utils.SupportedSocials, handle, socialCode string) (string, error) {
+	provider, ok := socials.Provider(socialCode)
+	if !ok {
+		return "", fmt.Errorf("unknown social code %s", socialCode)
+	}
+	handle = provider.NormalizeHandle(handle)
+	if err := provider.ValidateHandle(handle); err != nil {
+		return "", err
+	}
+	return provider.AvatarURL(handle)
+}
+
+// definition jumps from an alias or a username to the artist it belongs to, in
+// any open document
+func (server *Server) definition(doc *document, pos artist.Position) []Location {
+	value, _ := doc.fieldAt(pos)
+	value = utils.NormalizeName(value)
+	if value == "" {
+		return make([]Location, 0)
+	}
+
+	locations := make([]Location, 0)
+	for _, other := range server.documents {
+		for _, parsed := range other.artists {
+			isAlias := false
+			for _, alias := range parsed.Aliases {
+				isAlias = isAlias || alias == value
+			}
+			if parsed.Username != value && !isAlias {
+				continue
+			}
+			locations = append(locations, Location{
+				URI:   other.uri,
+				Range: other.fieldRange(parsed.UsernamePosition()),
+			})
+		}
+	}
+	return locations
+}
+
+// formatting replaces the whole document with its canonical layout, unless it
+// contains errors
+func (server *Server) formatting(doc *document) []TextEdit {
+	formatted, diags := artist.Format(doc.text, artist.FormatOptions{
+		SupportedSocials: server.appState.SupportedSocials,
+	})
+	if formatted == doc.text || diags.HasErrors() {
+		return make([]TextEdit, 0)
+	}
+	return []TextEdit{{Range: doc.wholeRange(), NewText: formatted}}
+}
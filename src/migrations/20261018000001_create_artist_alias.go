@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+func init() {
+	Migrations.MustRegister(func(ctx context.Context, db *bun.DB) error {
+		// the tables used to be recreated on every start, their content is
+		// parsed again from the artists files
+		return execInTx(ctx, db,
+			`DROP TABLE IF EXISTS alias`,
+			`DROP TABLE IF EXISTS artist`,
+			`CREATE TABLE artist (
+				id VARCHAR NOT NULL PRIMARY KEY,
+				display_name VARCHAR NOT NULL DEFAULT '',
+				avatar VARCHAR NOT NULL DEFAULT '',
+				hash VARCHAR NOT NULL DEFAULT ''
+			)`,
+			`CREATE TABLE alias (
+				alias VARCHAR NOT NULL PRIMARY KEY,
+				artist_id VARCHAR NOT NULL REFERENCES artist (id) ON DELETE CASCADE
+			)`,
+			`CREATE INDEX alias_artist_id_idx ON alias (artist_id)`,
+		)
+	}, func(ctx context.Context, db *bun.DB) error {
+		return execInTx(ctx, db,
+			`DROP TABLE IF EXISTS alias`,
+			`DROP TABLE IF EXISTS artist`,
+		)
+	})
+}
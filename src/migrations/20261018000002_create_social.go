@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+func init() {
+	Migrations.MustRegister(func(ctx context.Context, db *bun.DB) error {
+		return execInTx(ctx, db,
+			`CREATE TABLE social (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				artist_id VARCHAR NOT NULL REFERENCES artist (id) ON DELETE CASCADE,
+				position INTEGER NOT NULL,
+				social_code VARCHAR NOT NULL DEFAULT '',
+				handle VARCHAR NOT NULL DEFAULT '',
+				link VARCHAR NOT NULL,
+				description VARCHAR NOT NULL,
+				is_special BOOLEAN NOT NULL DEFAULT FALSE
+			)`,
+			`CREATE UNIQUE INDEX social_artist_id_position_idx ON social (artist_id, position)`,
+			`CREATE INDEX social_social_code_idx ON social (social_code)`,
+		)
+	}, func(ctx context.Context, db *bun.DB) error {
+		return execInTx(ctx, db,
+			`DROP TABLE IF EXISTS social`,
+		)
+	})
+}
@@ -0,0 +1,56 @@
+package migrations
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/migrate"
+)
+
+// Migrations holds the versioned schema changes, one file per version named
+// <timestamp>_<name>.go
+var Migrations = migrate.NewMigrations()
+
+// Migrate applies the migrations that haven't been applied to db yet
+func Migrate(ctx context.Context, db *bun.DB) error {
+	group, err := migrateDB(ctx, db)
+	if err != nil {
+		return err
+	}
+	if !group.IsZero() {
+		slog.Info("database migrated", "group", group.ID, "migrations", group.Migrations.String())
+	}
+	return nil
+}
+
+// Create applies every migration to an empty throwaway database, without
+// logging
+func Create(ctx context.Context, db *bun.DB) error {
+	_, err := migrateDB(ctx, db)
+	return err
+}
+
+func migrateDB(ctx context.Context, db *bun.DB) (*migrate.MigrationGroup, error) {
+	migrator := migrate.NewMigrator(db, Migrations, migrate.WithMarkAppliedOnSuccess(true))
+	if err := migrator.Init(ctx); err != nil {
+		return nil, err
+	}
+	if err := migrator.Lock(ctx); err != nil {
+		return nil, err
+	}
+	defer migrator.Unlock(ctx)
+	return migrator.Migrate(ctx)
+}
+
+// execInTx runs the statements of one migration in a transaction
+func execInTx(ctx context.Context, db *bun.DB, queries ...string) error {
+	return db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		for _, query := range queries {
+			if _, err := tx.ExecContext(ctx, query); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
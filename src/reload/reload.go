@@ -0,0 +1,173 @@
+package reload
+
+import (
+	"artistdb-go/src/artist"
+	"artistdb-go/src/utils"
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Reloader is the only way the artists files are parsed into the database. It
+// runs one reload at a time, waits for bursts of triggers to settle and merges
+// the triggers that arrive while a reload is running into one more reload.
+type Reloader struct {
+	appState *utils.AppState
+	debounce time.Duration
+
+	// held for the whole duration of a reload
+	mu      sync.Mutex
+	trigger chan string
+	// what Run calls, ReloadNow except in tests
+	reload func()
+
+	status Status
+	// usernames and aliases served, the candidates of the suggestions on the
+	// not found page, loaded after every reload rather than on every 404
+	aliases  []string
+	statusMu sync.RWMutex
+}
+
+func NewReloader(appState *utils.AppState) *Reloader {
+	r := &Reloader{
+		appState: appState,
+		debounce: appState.GetReloadDebounce(),
+		trigger:  make(chan string, 1),
+	}
+	r.reload = func() { r.ReloadNow() }
+	return r
+}
+
+// Trigger asks for a reload without waiting for it. If one is already queued,
+// the two are merged.
+func (r *Reloader) Trigger(reason string) {
+	select {
+	case r.trigger <- reason:
+	default:
+	}
+}
+
+// Run reloads on every trigger until ctx is done, after the triggers have been
+// quiet for the debounce duration
+func (r *Reloader) Run(ctx context.Context) {
+	for {
+		var reason string
+		select {
+		case <-ctx.Done():
+			return
+		case reason = <-r.trigger:
+		}
+
+		timer := time.NewTimer(r.debounce)
+	debounce:
+		for {
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-r.trigger:
+				// with go 1.22 semantics a tick that fired meanwhile stays in
+				// the channel and would end the debounce early, drain it
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+				timer.Reset(r.debounce)
+			case <-timer.C:
+				break debounce
+			}
+		}
+
+		slog.Info("reloading", "reason", reason)
+		r.reload()
+	}
+}
+
+// ReloadNow parses the artists files into the database, waiting for any
+// running reload to finish first, and records the result. If anything fails,
+// the last good data stays online.
+func (r *Reloader) ReloadNow() Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// a failed reload keeps serving the data of the last good one, which could
+	// come from before a restart
+	defer r.loadAliases()
+
+	startTimer := time.Now()
+	var result artist.ParseResult
+	fail := func(message string, props ...any) Status {
+		slog.Error(message, props...)
+		slog.Warn("reload failed, keep serving the last good data")
+		return r.setStatus(Status{
+			Time:        startTimer,
+			Duration:    time.Since(startTimer).String(),
+			Failed:      true,
+			Error:       message,
+			SourceHash:  result.SourceHash,
+			Diagnostics: result.Diagnostics,
+		})
+	}
+
+	inFiles, err := r.appState.GetInFiles()
+	if err != nil {
+		return fail(err.Error())
+	}
+	sources, err := artist.ReadSources(inFiles)
+	if err != nil {
+		return fail(err.Error())
+	}
+	result, err = artist.ParseToNewDB(r.appState, sources)
+	result.Diagnostics.Log()
+	var diags artist.Diagnostics
+	switch {
+	case errors.As(err, &diags):
+		return fail("artists files contain errors",
+			"errors", result.Diagnostics.Count(artist.SeverityError),
+			"warnings", result.Diagnostics.Count(artist.SeverityWarning))
+	case err != nil:
+		return fail(err.Error())
+	}
+	status := r.setStatus(Status{
+		Time:        startTimer,
+		Duration:    time.Since(startTimer).String(),
+		ArtistCount: result.ArtistCount,
+		AliasCount:  result.AliasCount,
+		SourceHash:  result.SourceHash,
+		Diagnostics: result.Diagnostics,
+		Skipped:     result.Skipped,
+	})
+	if len(result.Skipped) > 0 {
+		slog.Warn("skipped artists and socials with errors, fix them in the artists files",
+			"count", len(result.Skipped))
+	}
+	slog.Info("parsed artists successfully", "count", result.ArtistCount, "files", len(inFiles))
+	r.export()
+	return status
+}
+
+// Export writes the artists served to OUT_DIR, if it's set. ReloadNow does it
+// after every successful reload; on startup it's also needed when the first
+// reload fails, as the database can still hold the data of the last run.
+func (r *Reloader) Export() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.export()
+}
+
+func (r *Reloader) export() {
+	outDir := r.appState.GetOutDir()
+	if outDir == "" {
+		return
+	}
+	startTimer := time.Now()
+	changed, err := artist.Export(context.Background(), r.appState.DB, outDir)
+	if err != nil {
+		slog.Error(err.Error())
+		return
+	}
+	slog.Info("exported artists", "dir", outDir, "changed", changed, "time", time.Since(startTimer))
+}
@@ -0,0 +1,122 @@
+package reload
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+const testDebounce = 50 * time.Millisecond
+
+// newTestReloader returns a Reloader whose reloads only report on the returned
+// channel, blocking until release is closed if it isn't nil
+func newTestReloader(release chan struct{}) (*Reloader, chan struct{}) {
+	reloads := make(chan struct{}, 16)
+	r := &Reloader{
+		debounce: testDebounce,
+		trigger:  make(chan string, 1),
+	}
+	r.reload = func() {
+		reloads <- struct{}{}
+		if release != nil {
+			<-release
+		}
+	}
+	return r, reloads
+}
+
+// countReloads counts the reloads until none happened for a while
+func countReloads(reloads chan struct{}) int {
+	count := 0
+	for {
+		select {
+		case <-reloads:
+			count++
+		case <-time.After(4 * testDebounce):
+			return count
+		}
+	}
+}
+
+func TestRunDebounces(t *testing.T) {
+	tests := []struct {
+		name     string
+		triggers int
+		gap      time.Duration
+		want     int
+	}{
+		{"one trigger", 1, 0, 1},
+		{"burst", 10, 0, 1},
+		{"triggers closer than the debounce", 5, testDebounce / 2, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			r, reloads := newTestReloader(nil)
+			go r.Run(ctx)
+
+			start := time.Now()
+			for i := 0; i < test.triggers; i++ {
+				r.Trigger("test")
+				time.Sleep(test.gap)
+			}
+			select {
+			case <-reloads:
+			case <-time.After(time.Second):
+				t.Fatal("no reload")
+			}
+			if elapsed := time.Since(start); elapsed < testDebounce {
+				t.Errorf("reloaded after %v, before the debounce of %v", elapsed, testDebounce)
+			}
+			if got := 1 + countReloads(reloads); got != test.want {
+				t.Errorf("%d reloads, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRunMergesTriggersDuringReload(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	release := make(chan struct{})
+	r, reloads := newTestReloader(release)
+	go r.Run(ctx)
+
+	r.Trigger("first")
+	select {
+	case <-reloads:
+	case <-time.After(time.Second):
+		t.Fatal("no reload")
+	}
+	// the reload is running, Trigger must not block and the triggers must
+	// end up in a single follow-up reload
+	for i := 0; i < 10; i++ {
+		r.Trigger("during")
+	}
+	close(release)
+	if got := countReloads(reloads); got != 1 {
+		t.Errorf("%d reloads after the running one, want 1", got)
+	}
+}
+
+func TestRunStopsWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r, reloads := newTestReloader(nil)
+	done := make(chan struct{})
+	go func() {
+		r.Run(ctx)
+		close(done)
+	}()
+
+	r.Trigger("cancelled")
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run didn't return")
+	}
+	if got := countReloads(reloads); got != 0 {
+		t.Errorf("%d reloads after cancel, want 0", got)
+	}
+}
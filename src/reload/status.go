@@ -0,0 +1,72 @@
+package reload
+
+import (
+	"artistdb-go/src/artist"
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Status is the result of the last attempt to parse the artists files into the
+// database
+type Status struct {
+	Time        time.Time `json:"time"`
+	Duration    string    `json:"duration"`
+	ArtistCount int       `json:"artistCount"`
+	AliasCount  int       `json:"aliasCount"`
+	// sha256 of the artists files, see artist.HashSources
+	SourceHash  string             `json:"sourceHash"`
+	Failed      bool               `json:"failed"`
+	Error       string             `json:"error,omitempty"`
+	Diagnostics artist.Diagnostics `json:"diagnostics"`
+	// artists and socials left out in lenient mode
+	Skipped []artist.Skipped `json:"skipped"`
+}
+
+func (r *Reloader) GetStatus() Status {
+	r.statusMu.RLock()
+	defer r.statusMu.RUnlock()
+	return r.status
+}
+
+// setStatus records the result of a reload. A failed reload keeps the artist
+// count of the last good one, since that data is still being served.
+func (r *Reloader) setStatus(status Status) Status {
+	r.statusMu.Lock()
+	defer r.statusMu.Unlock()
+	if status.Failed {
+		status.ArtistCount = r.status.ArtistCount
+		status.AliasCount = r.status.AliasCount
+	}
+	if status.Diagnostics == nil {
+		status.Diagnostics = make(artist.Diagnostics, 0)
+	}
+	if status.Skipped == nil {
+		status.Skipped = make([]artist.Skipped, 0)
+	}
+	r.status = status
+	return status
+}
+
+// Aliases returns the usernames and aliases served, as of the last reload
+func (r *Reloader) Aliases() []string {
+	r.statusMu.RLock()
+	defer r.statusMu.RUnlock()
+	return r.aliases
+}
+
+// loadAliases reads the usernames and aliases served from the database
+func (r *Reloader) loadAliases() {
+	aliases := make([]string, 0)
+	err := r.appState.DB.NewSelect().
+		Model((*artist.AliasDB)(nil)).
+		Column("alias").
+		Scan(context.Background(), &aliases)
+	if err != nil {
+		slog.Error("failed to get aliases for suggestions", "err", err)
+		return
+	}
+	r.statusMu.Lock()
+	defer r.statusMu.Unlock()
+	r.aliases = aliases
+}
@@ -2,6 +2,7 @@ package routes
 
 import (
 	"artistdb-go/src/artist"
+	"artistdb-go/src/reload"
 	"artistdb-go/src/utils"
 	"database/sql"
 	"errors"
@@ -10,17 +11,19 @@ import (
 	"log/slog"
 	"net/http"
 	"strings"
+
+	"github.com/uptrace/bun"
 )
 
-func GetArtist(appState *utils.AppState) func(w http.ResponseWriter, r *http.Request) {
+func GetArtist(appState *utils.AppState, reloader *reload.Reloader) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		username := strings.ToLower(r.PathValue("username"))
+		username := utils.NormalizeName(r.PathValue("username"))
 
 		aliasModel := new(artist.AliasDB)
 		err := appState.DB.NewSelect().Model(aliasModel).Where("alias = ?", username).Scan(r.Context())
 		if err != nil {
 			if errors.Is(err, sql.ErrNoRows) {
-				appState.ArtistNotFoundTmpl.Tmpl.Execute(w, nil)
+				artistNotFound(appState, reloader, w, username)
 				return
 			}
 			slog.Error("failed to get artist", "err", err)
@@ -29,11 +32,17 @@ func GetArtist(appState *utils.AppState) func(w http.ResponseWriter, r *http.Req
 		}
 
 		artistModel := new(artist.ArtistDB)
-		err = appState.DB.NewSelect().Model(artistModel).Where("id = ?", aliasModel.ID).Scan(r.Context())
+		err = appState.DB.NewSelect().
+			Model(artistModel).
+			Relation("Socials", func(q *bun.SelectQuery) *bun.SelectQuery {
+				return q.Order("position")
+			}).
+			Where("?TableAlias.id = ?", aliasModel.ID).
+			Scan(r.Context())
 		if err != nil {
 			if errors.Is(err, sql.ErrNoRows) {
 				slog.Error("alias found but artist not found", "alias", username)
-				appState.ArtistNotFoundTmpl.Tmpl.Execute(w, nil)
+				artistNotFound(appState, reloader, w, username)
 				return
 			}
 			slog.Error("failed to get artist", "err", err)
@@ -41,24 +50,12 @@ func GetArtist(appState *utils.AppState) func(w http.ResponseWriter, r *http.Req
 			return
 		}
 
-		socialLines := strings.Split(artistModel.Socials, "\n")
-		socials := make([]template.HTML, 0, len(socialLines))
-		for _, socialLine := range socialLines {
-			isSpecial := false
-			if strings.HasPrefix(socialLine, "*") {
-				isSpecial = true
-				socialLine = socialLine[1:]
-			}
-			socialInfo := strings.Split(socialLine, ",")
-			if len(socialInfo) != 2 {
-				http.Error(w, "DB contains invalid social line", http.StatusInternalServerError)
-				slog.Error("invalid social line", "artist", username, "line", socialLine)
-				return
-			}
+		socials := make([]template.HTML, 0, len(artistModel.Socials))
+		for _, social := range artistModel.Socials {
 			socials = append(socials, appState.SocialLinkTmpl.RenderAsHTML(utils.LinkPageFields{
-				IsSpecial:   isSpecial,
-				Link:        socialInfo[0],
-				Description: socialInfo[1],
+				IsSpecial:   social.IsSpecial,
+				Link:        social.Link,
+				Description: social.Description,
 			}))
 		}
 
@@ -75,3 +72,12 @@ func GetArtist(appState *utils.AppState) func(w http.ResponseWriter, r *http.Req
 		})
 	}
 }
+
+// artistNotFound renders the not found page with the usernames and aliases
+// closest to the one requested
+func artistNotFound(appState *utils.AppState, reloader *reload.Reloader, w http.ResponseWriter, username string) {
+	appState.ArtistNotFoundTmpl.Execute(w, utils.NotFoundPageFields{
+		Username:    username,
+		Suggestions: utils.Suggest(username, reloader.Aliases()),
+	})
+}
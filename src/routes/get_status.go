@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"artistdb-go/src/reload"
+	"artistdb-go/src/utils"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+func GetStatus(reloader *reload.Reloader) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(reloader.GetStatus()); err != nil {
+			slog.Error("can't encode reload status", "err", err)
+		}
+	}
+}
+
+// GetStatusPage renders the result of the last reload for operators
+func GetStatusPage(appState *utils.AppState, reloader *reload.Reloader) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		appState.StatusPageTmpl.Execute(w, reloader.GetStatus())
+	}
+}
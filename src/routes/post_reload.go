@@ -0,0 +1,48 @@
+package routes
+
+import (
+	"artistdb-go/src/reload"
+	"artistdb-go/src/utils"
+	"crypto/subtle"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// isAdmin checks the Authorization: Bearer <ADMIN_TOKEN> header, writing the
+// error response if it's missing or wrong. Admin routes are disabled if
+// ADMIN_TOKEN is not set.
+func isAdmin(appState *utils.AppState, w http.ResponseWriter, r *http.Request) bool {
+	adminToken := appState.GetAdminToken()
+	if adminToken == "" {
+		http.Error(w, "admin routes are disabled, set ADMIN_TOKEN to enable them", http.StatusNotFound)
+		return false
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) != 1 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// PostReload parses the artists files right away and responds with the result
+func PostReload(appState *utils.AppState, reloader *reload.Reloader) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !isAdmin(appState, w, r) {
+			return
+		}
+
+		slog.Info("reloading", "reason", "admin reload from "+r.RemoteAddr)
+		status := reloader.ReloadNow()
+		w.Header().Set("Content-Type", "application/json")
+		if status.Failed {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		}
+		if err := json.NewEncoder(w).Encode(status); err != nil {
+			slog.Error("can't encode reload status", "err", err)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package routes
+
+import (
+	"artistdb-go/src/artist"
+	"artistdb-go/src/utils"
+	"artistdb-go/src/validate"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// MAX_VALIDATE_SIZE is the largest artists file POST /api/validate accepts
+const MAX_VALIDATE_SIZE = 8 << 20
+
+// PostValidate parses the artists file in the request body without touching
+// the live data, and responds with the problems found and the artists that
+// would change. The body replaces one of the live artists files, named by the
+// file query parameter relative to IN_FILE when it's a directory, so the other
+// files are checked with it and their artists aren't reported as removed. It
+// needs the admin token, parsing up to 8 MiB is expensive.
+func PostValidate(appState *utils.AppState) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !isAdmin(appState, w, r) {
+			return
+		}
+		rawBytes, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MAX_VALIDATE_SIZE))
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				http.Error(w, "artists file too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, "can't read request body", http.StatusBadRequest)
+			return
+		}
+
+		sources, err := overlaySources(appState.GetInFile(), r.URL.Query().Get("file"), string(rawBytes))
+		if err != nil {
+			var badRequest badRequestError
+			if errors.As(err, &badRequest) {
+				http.Error(w, badRequest.Error(), http.StatusBadRequest)
+				return
+			}
+			slog.Error("can't read the live artists files", "err", err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		result, err := validate.Sources(r.Context(), appState, appState.DB, sources)
+		if err != nil {
+			slog.Error("failed to validate artists", "err", err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if !result.Valid {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		}
+		if err := json.NewEncoder(w).Encode(result); err != nil {
+			slog.Error("can't encode validation result", "err", err)
+		}
+	}
+}
+
+type badRequestError string
+
+func (err badRequestError) Error() string {
+	return string(err)
+}
+
+// overlaySources returns the live artists files with the one named file
+// replaced by content, or added if it doesn't exist yet
+func overlaySources(inFile, file, content string) ([]artist.Source, error) {
+	fileStat, err := os.Stat(inFile)
+	if err != nil {
+		return nil, err
+	}
+	if !fileStat.IsDir() {
+		if file != "" && file != filepath.Base(inFile) {
+			return nil, badRequestError("file must be " + filepath.Base(inFile) + ", the only artists file")
+		}
+		return []artist.Source{{Name: inFile, Content: content}}, nil
+	}
+
+	if file == "" {
+		return nil, badRequestError("file is required, IN_FILE is a directory")
+	}
+	path := filepath.Join(inFile, filepath.FromSlash(file))
+	if filepath.IsAbs(file) || !utils.IsInFileUnder(inFile, path) {
+		return nil, badRequestError("file must be a " + utils.IN_FILE_EXT + " file inside IN_FILE, not in a hidden directory")
+	}
+	inFiles, err := utils.ListInFiles(inFile)
+	if err != nil {
+		return nil, err
+	}
+	sources, err := artist.ReadSources(inFiles)
+	if err != nil {
+		return nil, err
+	}
+	for i := range sources {
+		if sources[i].Name == path {
+			sources[i].Content = content
+			return sources, nil
+		}
+	}
+	return append(sources, artist.Source{Name: path, Content: content}), nil
+}
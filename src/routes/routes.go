@@ -0,0 +1,92 @@
+package routes
+
+import (
+	"artistdb-go/src/reload"
+	"artistdb-go/src/utils"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+type handlerFunc = func(w http.ResponseWriter, r *http.Request)
+
+// Route is one route of the server, Handler builds its handler
+type Route struct {
+	Pattern string
+	Handler func(appState *utils.AppState, reloader *reload.Reloader) handlerFunc
+}
+
+// ROUTES lists every route of the server. The artist pages are mounted at
+// GET /{username}, so the first segment of every other route is reserved, see
+// ReservedSegments.
+var ROUTES = []Route{
+	{"GET /", func(*utils.AppState, *reload.Reloader) handlerFunc {
+		return GetIndex
+	}},
+	{"GET /{username}", func(appState *utils.AppState, reloader *reload.Reloader) handlerFunc {
+		return GetArtist(appState, reloader)
+	}},
+	{"GET /style.css", func(*utils.AppState, *reload.Reloader) handlerFunc {
+		return StyleCSS
+	}},
+	{"GET /avatar/{fileName}", func(appState *utils.AppState, _ *reload.Reloader) handlerFunc {
+		return GetAvatar(appState)
+	}},
+	{"GET /font/{fontName}", func(*utils.AppState, *reload.Reloader) handlerFunc {
+		return GetFont
+	}},
+	{"GET /api/status", func(_ *utils.AppState, reloader *reload.Reloader) handlerFunc {
+		return GetStatus(reloader)
+	}},
+	{"POST /api/validate", func(appState *utils.AppState, _ *reload.Reloader) handlerFunc {
+		return PostValidate(appState)
+	}},
+	{"GET /status", func(appState *utils.AppState, reloader *reload.Reloader) handlerFunc {
+		return GetStatusPage(appState, reloader)
+	}},
+	{"POST /admin/reload", func(appState *utils.AppState, reloader *reload.Reloader) handlerFunc {
+		return PostReload(appState, reloader)
+	}},
+}
+
+// RESERVED_EXTRA are reserved on top of the routes: files browsers and
+// crawlers ask for, and segments kept for future routes
+var RESERVED_EXTRA = []string{
+	".well-known",
+	"assets",
+	"favicon.ico",
+	"robots.txt",
+	"sitemap.xml",
+	"static",
+}
+
+// Register mounts every route on the default ServeMux
+func Register(appState *utils.AppState, reloader *reload.Reloader) {
+	for _, route := range ROUTES {
+		http.HandleFunc(route.Pattern, route.Handler(appState, reloader))
+	}
+}
+
+// ReservedSegments returns the path segments usernames and aliases can't use,
+// because a route would shadow them
+func ReservedSegments() []string {
+	segmentSet := make(map[string]struct{})
+	for _, route := range ROUTES {
+		_, path, _ := strings.Cut(route.Pattern, " ")
+		segment, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+		if segment == "" || strings.HasPrefix(segment, "{") {
+			continue
+		}
+		segmentSet[segment] = struct{}{}
+	}
+	for _, segment := range RESERVED_EXTRA {
+		segmentSet[segment] = struct{}{}
+	}
+
+	segments := make([]string, 0, len(segmentSet))
+	for segment := range segmentSet {
+		segments = append(segments, segment)
+	}
+	sort.Strings(segments)
+	return segments
+}
@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReservedSegments(t *testing.T) {
+	// the list in the README
+	want := []string{".well-known", "admin", "api", "assets", "avatar", "favicon.ico", "font", "robots.txt", "sitemap.xml", "static", "status", "style.css"}
+	if got := ReservedSegments(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ReservedSegments() = %v, want %v", got, want)
+	}
+}
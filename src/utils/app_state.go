@@ -4,7 +4,10 @@ import (
 	"database/sql"
 	"log/slog"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/uptrace/bun"
 	"github.com/uptrace/bun/dialect/sqlitedialect"
@@ -14,23 +17,31 @@ import (
 type AppState struct {
 	port      string
 	inFile    string
+	outDir    string
 	avatarDir string
 
+	adminToken  string
+	parseMode   string
+	socialsFile string
+
+	reloadDebounce    time.Duration
+	watchMode         string
+	watchPollInterval time.Duration
+
 	SocialLinkTmpl     *HTMLTemplate
 	ArtistPageTmpl     *HTMLTemplate
 	ArtistNotFoundTmpl *HTMLTemplate
+	StatusPageTmpl     *HTMLTemplate
 
-	// To check duplicate usernames and aliases
-	UsernameSet map[string]struct{}
-	AliasSet    map[string]struct{}
-
-	SupportedSocials SupportedSocials
+	SupportedSocials *SupportedSocials
+	// path segments taken by the routes, which usernames and aliases can't use
+	ReservedSegments []string
 
 	DB *bun.DB
 }
 
 func NewAppState() *AppState {
-	return &AppState{
+	appState := &AppState{
 		port: func() string {
 			port := os.Getenv("PORT")
 			portInt, err := strconv.Atoi(port)
@@ -49,16 +60,23 @@ func NewAppState() *AppState {
 			if inFile == "" {
 				return "artists.txt"
 			}
-			fileStat, err := os.Stat(inFile)
-			if err != nil {
+			if _, err := os.Stat(inFile); err != nil {
 				slog.Error(err.Error())
 				os.Exit(1)
 			}
-			if fileStat.IsDir() {
-				slog.Error(inFile + " is a directory")
+			return inFile
+		}(),
+		outDir: func() string {
+			outDir := os.Getenv("OUT_DIR")
+			if outDir == "" {
+				return ""
+			}
+			fileStat, err := os.Stat(outDir)
+			if err == nil && !fileStat.IsDir() {
+				slog.Error(outDir + " is not a directory")
 				os.Exit(1)
 			}
-			return inFile
+			return outDir
 		}(),
 		avatarDir: func() string {
 			avatarDir := os.Getenv("AVATAR_DIR")
@@ -76,6 +94,45 @@ func NewAppState() *AppState {
 			}
 			return avatarDir
 		}(),
+		adminToken: os.Getenv("ADMIN_TOKEN"),
+		parseMode:  parseModeFromEnv(),
+		reloadDebounce: func() time.Duration {
+			reloadDebounce := os.Getenv("RELOAD_DEBOUNCE")
+			if reloadDebounce == "" {
+				return 500 * time.Millisecond
+			}
+			duration, err := time.ParseDuration(reloadDebounce)
+			if err != nil || duration < 0 {
+				slog.Error("invalid reload debounce, must be a duration like 500ms")
+				os.Exit(1)
+			}
+			return duration
+		}(),
+		watchMode: func() string {
+			watchMode := os.Getenv("WATCH_MODE")
+			switch watchMode {
+			case "":
+				return "notify"
+			case "notify", "poll":
+				return watchMode
+			default:
+				slog.Error("invalid watch mode, must be notify or poll")
+				os.Exit(1)
+				return ""
+			}
+		}(),
+		watchPollInterval: func() time.Duration {
+			watchPollInterval := os.Getenv("WATCH_POLL_INTERVAL")
+			if watchPollInterval == "" {
+				return 2 * time.Second
+			}
+			duration, err := time.ParseDuration(watchPollInterval)
+			if err != nil || duration <= 0 {
+				slog.Error("invalid watch poll interval, must be a duration like 2s")
+				os.Exit(1)
+			}
+			return duration
+		}(),
 
 		SocialLinkTmpl: func() *HTMLTemplate {
 			st := &HTMLTemplate{}
@@ -101,23 +158,147 @@ func NewAppState() *AppState {
 			}
 			return st
 		}(),
-
-		UsernameSet:      make(map[string]struct{}),
-		AliasSet:         make(map[string]struct{}),
-		SupportedSocials: NewSocialDBInstance(),
-
-		DB: func() *bun.DB {
-			sqldbPath := os.Getenv("SQLITE")
-			if sqldbPath == "" {
-				sqldbPath = "./sqlite.db?mode=rwc"
-			}
-			sqldb, err := sql.Open(sqliteshim.ShimName, sqldbPath)
-			if err != nil {
+		StatusPageTmpl: func() *HTMLTemplate {
+			st := &HTMLTemplate{}
+			if err := st.Read("./frontend/status.html"); err != nil {
 				slog.Error(err.Error())
 				os.Exit(1)
 			}
-			return bun.NewDB(sqldb, sqlitedialect.New())
+			return st
 		}(),
+
+		socialsFile:      os.Getenv("SOCIALS_FILE"),
+		SupportedSocials: supportedSocialsFromEnv(),
+	}
+
+	// the export only removes files it wrote, but it would still overwrite an
+	// input file named like an artist
+	if appState.outDir != "" {
+		for name, path := range map[string]string{
+			"IN_FILE":      appState.inFile,
+			"SQLITE":       SQLitePath(os.Getenv("SQLITE")),
+			"SOCIALS_FILE": appState.socialsFile,
+		} {
+			if path != "" && IsInside(appState.outDir, path) {
+				slog.Error(name+" can't be inside OUT_DIR", "path", path, "outDir", appState.outDir)
+				os.Exit(1)
+			}
+		}
+	}
+	appState.DB = openDB()
+	return appState
+}
+
+// openDB opens SQLITE, after the paths were checked so a misplaced database
+// isn't created
+func openDB() *bun.DB {
+	sqldbPath := os.Getenv("SQLITE")
+	if sqldbPath == "" {
+		sqldbPath = "./sqlite.db?mode=rwc"
+	}
+	sqldb, err := sql.Open(sqliteshim.ShimName, SQLiteDSN(sqldbPath))
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+	// readers keep seeing the old tables while a reload swaps them
+	if _, err := sqldb.Exec("PRAGMA journal_mode=WAL"); err != nil {
+		slog.Warn("can't enable WAL mode", "err", err)
+	}
+	return bun.NewDB(sqldb, sqlitedialect.New())
+}
+
+// SQLitePath returns the file of an SQLite DSN like SQLITE, without the file:
+// scheme and the ?query, or ./sqlite.db if dsn is empty
+func SQLitePath(dsn string) string {
+	path, _, _ := strings.Cut(strings.TrimPrefix(dsn, "file:"), "?")
+	if path == "" {
+		return "./sqlite.db"
+	}
+	return path
+}
+
+// SQLiteDSN adds to dsn the pragmas every connection of the pool needs:
+// foreign keys are off by default in SQLite, and the schema relies on ON
+// DELETE CASCADE
+func SQLiteDSN(dsn string) string {
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+	return dsn + separator + "_pragma=foreign_keys(1)"
+}
+
+// IsInside reports whether path is dir or is inside it, once both are absolute
+// and their symlinks are resolved
+func IsInside(dir, path string) bool {
+	resolve := func(path string) string {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return path
+		}
+		if resolved, err := filepath.EvalSymlinks(absPath); err == nil {
+			return resolved
+		}
+		// a file that doesn't exist yet, resolve its directory
+		if resolvedDir, err := filepath.EvalSymlinks(filepath.Dir(absPath)); err == nil {
+			return filepath.Join(resolvedDir, filepath.Base(absPath))
+		}
+		return absPath
+	}
+	rel, err := filepath.Rel(resolve(dir), resolve(path))
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+// NewParseAppState only reads what parsing the artists files needs, for the
+// commands that don't serve anything
+func NewParseAppState() *AppState {
+	return &AppState{
+		parseMode:        parseModeFromEnv(),
+		socialsFile:      os.Getenv("SOCIALS_FILE"),
+		SupportedSocials: supportedSocialsFromEnv(),
+	}
+}
+
+// NewStrictParseAppState is NewParseAppState always parsing in strict mode,
+// whatever PARSE_MODE says, for the editor which must show every error
+func NewStrictParseAppState() *AppState {
+	return &AppState{
+		parseMode:        "strict",
+		socialsFile:      os.Getenv("SOCIALS_FILE"),
+		SupportedSocials: supportedSocialsFromEnv(),
+	}
+}
+
+// supportedSocialsFromEnv returns the built-in socials, plus the ones of
+// SOCIALS_FILE when it's set
+func supportedSocialsFromEnv() *SupportedSocials {
+	supportedSocials := NewSocialDBInstance()
+	socialsFile := os.Getenv("SOCIALS_FILE")
+	if socialsFile == "" {
+		return supportedSocials
+	}
+	if err := supportedSocials.Load(socialsFile); err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+	return supportedSocials
+}
+
+func parseModeFromEnv() string {
+	parseMode := os.Getenv("PARSE_MODE")
+	switch parseMode {
+	case "":
+		return "strict"
+	case "strict", "lenient":
+		return parseMode
+	default:
+		slog.Error("invalid parse mode, must be strict or lenient")
+		os.Exit(1)
+		return ""
 	}
 }
 
@@ -127,6 +308,30 @@ func (as *AppState) GetPort() string {
 func (as *AppState) GetInFile() string {
 	return as.inFile
 }
+func (as *AppState) GetInFiles() ([]string, error) {
+	return ListInFiles(as.inFile)
+}
+func (as *AppState) GetOutDir() string {
+	return as.outDir
+}
 func (as *AppState) GetAvatarDir() string {
 	return as.avatarDir
 }
+func (as *AppState) GetAdminToken() string {
+	return as.adminToken
+}
+func (as *AppState) GetParseMode() string {
+	return as.parseMode
+}
+func (as *AppState) GetSocialsFile() string {
+	return as.socialsFile
+}
+func (as *AppState) GetReloadDebounce() time.Duration {
+	return as.reloadDebounce
+}
+func (as *AppState) GetWatchMode() string {
+	return as.watchMode
+}
+func (as *AppState) GetWatchPollInterval() time.Duration {
+	return as.watchPollInterval
+}
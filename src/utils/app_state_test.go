@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsInside(t *testing.T) {
+	root := t.TempDir()
+	outDir := filepath.Join(root, "out")
+	if err := os.Mkdir(outDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// a link to outDir from outside of it
+	link := filepath.Join(root, "link")
+	if err := os.Symlink(outDir, link); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		path string
+		want bool
+	}{
+		{"the directory itself", outDir, true},
+		{"file inside", filepath.Join(outDir, "artists.txt"), true},
+		{"nested file", filepath.Join(outDir, "a", "b.txt"), true},
+		{"file next to it", filepath.Join(root, "artists.txt"), false},
+		{"name starting with ..", filepath.Join(outDir, "..artists.txt"), true},
+		{"parent directory", root, false},
+		{"sibling with the same prefix", outDir + "-old", false},
+		{"through a symlink", filepath.Join(link, "artists.txt"), true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := IsInside(outDir, test.path); got != test.want {
+				t.Errorf("IsInside(%q, %q) = %v, want %v", outDir, test.path, got, test.want)
+			}
+		})
+	}
+}
+
+func TestSQLitePath(t *testing.T) {
+	tests := []struct {
+		dsn  string
+		want string
+	}{
+		{"", "./sqlite.db"},
+		{"./data/artists.db", "./data/artists.db"},
+		{"./sqlite.db?mode=rwc", "./sqlite.db"},
+		{"file:./data/artists.db", "./data/artists.db"},
+		{"file:/var/lib/artistdb/sqlite.db?mode=rwc&_pragma=busy_timeout(5000)", "/var/lib/artistdb/sqlite.db"},
+	}
+	for _, test := range tests {
+		t.Run(test.dsn, func(t *testing.T) {
+			if got := SQLitePath(test.dsn); got != test.want {
+				t.Errorf("SQLitePath(%q) = %q, want %q", test.dsn, got, test.want)
+			}
+		})
+	}
+}
@@ -52,3 +52,8 @@ type LinkPageFields struct {
 	Link        string
 	Description string
 }
+
+type NotFoundPageFields struct {
+	Username    string
+	Suggestions []string
+}
@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const IN_FILE_EXT = ".txt"
+
+// ListInFiles returns the artists files to parse. inFile is either a single
+// file or a directory, in which case every .txt file in it and its
+// subdirectories is used, sorted by path. Hidden files and directories are
+// skipped.
+func ListInFiles(inFile string) ([]string, error) {
+	fileStat, err := os.Stat(inFile)
+	if err != nil {
+		return nil, err
+	}
+	if !fileStat.IsDir() {
+		return []string{inFile}, nil
+	}
+
+	files := make([]string, 0)
+	err = filepath.WalkDir(inFile, func(path string, d fs.DirEntry, err error) error {
+		switch {
+		case err != nil:
+			return err
+		case path != inFile && strings.HasPrefix(d.Name(), "."):
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		case d.IsDir():
+			return nil
+		case IsInFile(path):
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// IsInFile reports whether path is an artists file when IN_FILE is a directory
+func IsInFile(path string) bool {
+	return filepath.Ext(path) == IN_FILE_EXT && !strings.HasPrefix(filepath.Base(path), ".")
+}
+
+// IsInFileUnder reports whether path is an artists file ListInFiles(root)
+// would return, not under a hidden directory of root
+func IsInFileUnder(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return false
+	}
+	for _, segment := range strings.Split(filepath.Dir(rel), string(filepath.Separator)) {
+		if segment != "." && strings.HasPrefix(segment, ".") {
+			return false
+		}
+	}
+	return IsInFile(path)
+}
@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizeName turns a username or alias into the form it's stored and looked
+// up in: NFKC, so a composed é and e followed by a combining accent are the
+// same, and so are fullwidth and ASCII letters, then lowercase.
+func NormalizeName(name string) string {
+	return norm.NFKC.String(strings.ToLower(norm.NFKC.String(name)))
+}
+
+// CONFUSABLES maps the letters and digits most often mistaken for a Latin
+// letter to that letter. It's a curated subset of the Unicode confusables,
+// applied to normalized names.
+var CONFUSABLES = map[rune]rune{
+	// digits
+	'0': 'o',
+	'1': 'l',
+	// Cyrillic
+	'а': 'a', 'с': 'c', 'ԁ': 'd', 'е': 'e', 'ё': 'ë', 'һ': 'h', 'і': 'i',
+	'ї': 'ï', 'ј': 'j', 'ӏ': 'l', 'о': 'o', 'р': 'p', 'ԛ': 'q', 'ѕ': 's',
+	'у': 'y', 'ԝ': 'w', 'х': 'x',
+	// Greek
+	'α': 'a', 'ι': 'i', 'ν': 'v', 'ο': 'o', 'ρ': 'p', 'υ': 'u', 'γ': 'y',
+	// Latin lookalikes
+	'ı': 'i', 'ɑ': 'a', 'ɡ': 'g', 'ɩ': 'i',
+}
+
+// Skeleton maps every confusable character of a normalized name to the letter
+// it looks like, so two names with the same skeleton look identical to a human
+func Skeleton(name string) string {
+	return strings.Map(func(r rune) rune {
+		if replacement, ok := CONFUSABLES[r]; ok {
+			return replacement
+		}
+		return r
+	}, name)
+}
@@ -0,0 +1,45 @@
+package utils
+
+import "testing"
+
+func TestNormalizeName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"paul", "paul"},
+		{"Paul", "paul"},
+		{"café", "café"},
+		{"café", "café"},
+		{"ＰＡＵＬ", "paul"},
+		{"ﬁn", "fin"},
+		{"ΣΟΦΙΑ", "σοφια"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := NormalizeName(test.name); got != test.want {
+				t.Errorf("NormalizeName(%q) = %q, want %q", test.name, got, test.want)
+			}
+		})
+	}
+}
+
+func TestSkeleton(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"paul", "paul"},
+		{"pаul", "paul"},
+		{"pau1", "paul"},
+		{"ρаυl", "paul"},
+		{"ポール", "ポール"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Skeleton(test.name); got != test.want {
+				t.Errorf("Skeleton(%q) = %q, want %q", test.name, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// SocialProvider builds the links of one social from a handle. The links have
+// no scheme, like the profile links of the registry: //host/path for avatars,
+// host/path for profiles.
+type SocialProvider interface {
+	DisplayName() string
+	// NormalizeHandle returns the handle as the social writes it, e.g. without
+	// the case of subdomains. It's called before ValidateHandle.
+	NormalizeHandle(handle string) string
+	ValidateHandle(handle string) error
+	// ProfileURL is empty for the socials only used for avatars
+	ProfileURL(handle string) (string, error)
+	AvatarURL(handle string) (string, error)
+}
+
+// BUILTIN_PROVIDERS are the socials with their own rules, the others use the
+// profile template of the registry
+var BUILTIN_PROVIDERS = map[string]func(code string, social Social) SocialProvider{
+	"github":  newGitHubProvider,
+	"bluesky": newBlueskyProvider,
+}
+
+// newProvider returns the provider of a social of the registry
+func newProvider(code string, social Social) SocialProvider {
+	if newBuiltin, ok := BUILTIN_PROVIDERS[code]; ok {
+		return newBuiltin(code, social)
+	}
+	if strings.Contains(social.Profile, "<instance>") {
+		return &fediverseProvider{templateProvider{code, social}}
+	}
+	if strings.HasPrefix(social.Profile, "<@>.") {
+		return &subdomainProvider{templateProvider{code, social}}
+	}
+	return &templateProvider{code, social}
+}
+
+// templateProvider replaces <@> in the profile template, and asks unavatar.io
+// for the avatar if it supports the social
+type templateProvider struct {
+	code   string
+	social Social
+}
+
+func (provider *templateProvider) DisplayName() string {
+	return provider.social.DisplayName
+}
+
+func (provider *templateProvider) NormalizeHandle(handle string) string {
+	return strings.TrimSpace(handle)
+}
+
+func (provider *templateProvider) ValidateHandle(handle string) error {
+	if handle == "" {
+		return fmt.Errorf("handle is empty")
+	}
+	if i := strings.IndexFunc(handle, func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune("/?#@", r)
+	}); i >= 0 {
+		return fmt.Errorf("handle can't contain %q", handle[i:i+1])
+	}
+	return nil
+}
+
+func (provider *templateProvider) ProfileURL(handle string) (string, error) {
+	return strings.Replace(provider.social.Profile, "<@>", handle, 1), nil
+}
+
+func (provider *templateProvider) AvatarURL(handle string) (string, error) {
+	if !provider.social.Unavatar {
+		return "", fmt.Errorf("%s avatars aren't supported", provider.social.DisplayName)
+	}
+	return fmt.Sprintf("//unavatar.io/%s/%s", provider.code, handle), nil
+}
+
+// subdomainProvider is for the socials giving each profile a subdomain, like
+// <@>.tumblr.com, so the handle must be a DNS label
+type subdomainProvider struct {
+	templateProvider
+}
+
+func (provider *subdomainProvider) NormalizeHandle(handle string) string {
+	return strings.ToLower(strings.TrimSpace(handle))
+}
+
+func (provider *subdomainProvider) ValidateHandle(handle string) error {
+	if !IsDNSLabel(handle) {
+		return fmt.Errorf("handle must be a valid subdomain: letters, digits and -")
+	}
+	return nil
+}
+
+// gitHubProvider serves avatars straight from github.com
+type gitHubProvider struct {
+	templateProvider
+}
+
+func newGitHubProvider(code string, social Social) SocialProvider {
+	return &gitHubProvider{templateProvider{code, social}}
+}
+
+func (provider *gitHubProvider) ValidateHandle(handle string) error {
+	if len(handle) > 39 || !IsDNSLabel(handle) || strings.Contains(handle, "--") {
+		return fmt.Errorf("handle must be up to 39 letters, digits or single -, not at the start or end")
+	}
+	return nil
+}
+
+func (provider *gitHubProvider) AvatarURL(handle string) (string, error) {
+	return fmt.Sprintf("//github.com/%s.png", handle), nil
+}
+
+// blueskyProvider takes domain handles, a handle without a domain is on
+// bsky.social
+type blueskyProvider struct {
+	templateProvider
+}
+
+func newBlueskyProvider(code string, social Social) SocialProvider {
+	return &blueskyProvider{templateProvider{code, social}}
+}
+
+func (provider *blueskyProvider) NormalizeHandle(handle string) string {
+	handle = strings.ToLower(strings.TrimSpace(handle))
+	if handle != "" && !strings.Contains(handle, ".") {
+		handle += ".bsky.social"
+	}
+	return handle
+}
+
+func (provider *blueskyProvider) ValidateHandle(handle string) error {
+	if !IsHostname(handle) || !strings.Contains(handle, ".") {
+		return fmt.Errorf("handle must be a domain, e.g. paul.bsky.social")
+	}
+	return nil
+}
+
+// fediverseProvider is for the software of the fediverse, where a handle is
+// user@instance and <instance> in the profile template is replaced by the
+// instance. unavatar.io can't resolve these handles, so the avatar is read
+// from the profile page by microlink.
+type fediverseProvider struct {
+	templateProvider
+}
+
+func (provider *fediverseProvider) NormalizeHandle(handle string) string {
+	user, instance, _ := strings.Cut(strings.TrimPrefix(strings.TrimSpace(handle), "@"), "@")
+	if instance == "" {
+		return user
+	}
+	return user + "@" + strings.ToLower(instance)
+}
+
+func (provider *fediverseProvider) ValidateHandle(handle string) error {
+	user, instance, ok := strings.Cut(handle, "@")
+	if !ok {
+		return fmt.Errorf("handle must have the instance, e.g. paul@mastodon.art@%s", provider.code)
+	}
+	if user == "" || strings.IndexFunc(user, func(r rune) bool {
+		return !(unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_.-", r))
+	}) >= 0 {
+		return fmt.Errorf("user must only contain letters, digits, _, . and -")
+	}
+	if !IsHostname(instance) || !strings.Contains(instance, ".") {
+		return fmt.Errorf("instance must be a hostname, e.g. mastodon.art")
+	}
+	return nil
+}
+
+func (provider *fediverseProvider) ProfileURL(handle string) (string, error) {
+	user, instance, _ := strings.Cut(handle, "@")
+	profile := strings.Replace(provider.social.Profile, "<instance>", instance, 1)
+	return strings.Replace(profile, "<@>", user, 1), nil
+}
+
+func (provider *fediverseProvider) AvatarURL(handle string) (string, error) {
+	profile, err := provider.ProfileURL(handle)
+	if err != nil {
+		return "", err
+	}
+	return "//unavatar.io/microlink/https://" + profile, nil
+}
+
+// IsDNSLabel reports whether s can be one label of a hostname: 1 to 63 ASCII
+// letters, digits or -, not at the start or end
+func IsDNSLabel(s string) bool {
+	if len(s) == 0 || len(s) > 63 || s[0] == '-' || s[len(s)-1] == '-' {
+		return false
+	}
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// IsHostname reports whether s is a hostname made of DNS labels
+func IsHostname(s string) bool {
+	if len(s) == 0 || len(s) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(s, ".") {
+		if !IsDNSLabel(label) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestProviders(t *testing.T) {
+	socials := NewSocialDBInstance()
+	tests := []struct {
+		code       string
+		handle     string
+		normalized string
+		valid      bool
+		profile    string
+		// empty if the social has no avatars
+		avatar string
+	}{
+		// template
+		{"instagram", " paul ", "paul", true, "instagram.com/paul", "//unavatar.io/instagram/paul"},
+		{"instagram", "pa/ul", "pa/ul", false, "", ""},
+		{"instagram", "", "", false, "", ""},
+		{"instagram", "paul@mastodon.art", "paul@mastodon.art", false, "", ""},
+		{"artstation", "paul", "paul", true, "www.artstation.com/paul", ""},
+		// subdomain
+		{"tumblr", "Paul-Art", "paul-art", true, "paul-art.tumblr.com", ""},
+		{"tumblr", "paul_art", "paul_art", false, "", ""},
+		{"tumblr", "-paul", "-paul", false, "", ""},
+		// github
+		{"github", "paul", "paul", true, "github.com/paul", "//github.com/paul.png"},
+		{"github", "paul-art", "paul-art", true, "github.com/paul-art", "//github.com/paul-art.png"},
+		{"github", "paul--art", "paul--art", false, "", ""},
+		{"github", "paul-", "paul-", false, "", ""},
+		{"github", strings.Repeat("a", 40), strings.Repeat("a", 40), false, "", ""},
+		// bluesky
+		{"bluesky", "Paul", "paul.bsky.social", true, "bsky.app/profile/paul.bsky.social", ""},
+		{"bluesky", "paul.example.com", "paul.example.com", true, "bsky.app/profile/paul.example.com", ""},
+		{"bsky", "paul", "paul.bsky.social", true, "bsky.app/profile/paul.bsky.social", ""},
+		{"bluesky", "paul..com", "paul..com", false, "", ""},
+		{"bluesky", "pa_ul.com", "pa_ul.com", false, "", ""},
+		// fediverse
+		{"mastodon", "paul@Mastodon.ART", "paul@mastodon.art", true, "mastodon.art/@paul", "//unavatar.io/microlink/https://mastodon.art/@paul"},
+		{"mastodon", "@paul@mastodon.art", "paul@mastodon.art", true, "mastodon.art/@paul", "//unavatar.io/microlink/https://mastodon.art/@paul"},
+		{"misskey", "Paul_Art@misskey.io", "Paul_Art@misskey.io", true, "misskey.io/@Paul_Art", "//unavatar.io/microlink/https://misskey.io/@Paul_Art"},
+		{"pixelfed", "paul@pixelfed.social", "paul@pixelfed.social", true, "pixelfed.social/paul", "//unavatar.io/microlink/https://pixelfed.social/paul"},
+		{"mastodon", "paul", "paul", false, "", ""},
+		{"mastodon", "@mastodon.art", "mastodon.art", false, "", ""},
+		{"mastodon", "pa ul@mastodon.art", "pa ul@mastodon.art", false, "", ""},
+		{"mastodon", "paul@localhost", "paul@localhost", false, "", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.code+"/"+test.handle, func(t *testing.T) {
+			provider, ok := socials.Provider(test.code)
+			if !ok {
+				t.Fatalf("no provider for %s", test.code)
+			}
+			handle := provider.NormalizeHandle(test.handle)
+			if handle != test.normalized {
+				t.Errorf("NormalizeHandle(%q) = %q, want %q", test.handle, handle, test.normalized)
+			}
+			err := provider.ValidateHandle(handle)
+			if (err == nil) != test.valid {
+				t.Fatalf("ValidateHandle(%q) = %v, want valid %v", handle, err, test.valid)
+			}
+			if !test.valid {
+				return
+			}
+			if profile, err := provider.ProfileURL(handle); err != nil || profile != test.profile {
+				t.Errorf("ProfileURL(%q) = %q, %v, want %q", handle, profile, err, test.profile)
+			}
+			avatar, err := provider.AvatarURL(handle)
+			if (err == nil) != (test.avatar != "") || avatar != test.avatar {
+				t.Errorf("AvatarURL(%q) = %q, %v, want %q", handle, avatar, err, test.avatar)
+			}
+		})
+	}
+}
+
+func TestIsHostname(t *testing.T) {
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"mastodon.art", true},
+		{"paul.bsky.social", true},
+		{"localhost", true},
+		{"xn--caf-dma.example", true},
+		{"", false},
+		{"paul..com", false},
+		{".paul.com", false},
+		{"-paul.com", false},
+		{"paul_art.com", false},
+		{"café.example", false},
+		{strings.Repeat("a", 64) + ".com", false},
+	}
+	for _, test := range tests {
+		t.Run(test.host, func(t *testing.T) {
+			if got := IsHostname(test.host); got != test.want {
+				t.Errorf("IsHostname(%q) = %v, want %v", test.host, got, test.want)
+			}
+		})
+	}
+}
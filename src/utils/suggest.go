@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"sort"
+	"unicode/utf8"
+)
+
+// MAX_SUGGESTIONS is how many candidates Suggest returns at most
+const MAX_SUGGESTIONS = 3
+
+// Levenshtein returns the number of single character insertions, deletions and
+// substitutions needed to turn a into b
+func Levenshtein(a, b string) int {
+	runesA, runesB := []rune(a), []rune(b)
+	previous := make([]int, len(runesB)+1)
+	current := make([]int, len(runesB)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(runesA); i++ {
+		current[0] = i
+		for j := 1; j <= len(runesB); j++ {
+			cost := 1
+			if runesA[i-1] == runesB[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(runesB)]
+}
+
+// Suggest returns the candidates closest to target, nearest first. Candidates
+// too far away to be a typo are left out: at most 2 edits, or a third of the
+// length of target for longer ones. The lengths are compared first, so a long
+// target only costs a Levenshtein distance against candidates about as long.
+func Suggest(target string, candidates []string) []string {
+	type scored struct {
+		candidate string
+		distance  int
+	}
+	targetLength := utf8.RuneCountInString(target)
+	maxDistance := max(2, targetLength/3)
+	matches := make([]scored, 0)
+	for _, candidate := range candidates {
+		if candidate == target {
+			continue
+		}
+		// the distance is at least the difference of the lengths
+		lengthDiff := utf8.RuneCountInString(candidate) - targetLength
+		if lengthDiff > maxDistance || -lengthDiff > maxDistance {
+			continue
+		}
+		if distance := Levenshtein(target, candidate); distance <= maxDistance {
+			matches = append(matches, scored{candidate, distance})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].distance != matches[j].distance {
+			return matches[i].distance < matches[j].distance
+		}
+		return matches[i].candidate < matches[j].candidate
+	})
+
+	suggestions := make([]string, 0, MAX_SUGGESTIONS)
+	for _, match := range matches {
+		if len(suggestions) == MAX_SUGGESTIONS {
+			break
+		}
+		suggestions = append(suggestions, match.candidate)
+	}
+	return suggestions
+}
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"paul", "paul", 0},
+		{"", "paul", 4},
+		{"paul", "pual", 2},
+		{"instagarm", "instagram", 2},
+		{"kitten", "sitting", 3},
+		{"café", "cafe", 1},
+	}
+	for _, test := range tests {
+		t.Run(test.a+"/"+test.b, func(t *testing.T) {
+			if got := Levenshtein(test.a, test.b); got != test.want {
+				t.Errorf("Levenshtein(%q, %q) = %d, want %d", test.a, test.b, got, test.want)
+			}
+		})
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	codes := []string{"instagram", "x", "facebook", "github", "gitlab", "tumblr"}
+	tests := []struct {
+		name       string
+		target     string
+		candidates []string
+		want       []string
+	}{
+		{"typo", "instagarm", codes, []string{"instagram"}},
+		{"nearest first", "gitlib", codes, []string{"gitlab", "github"}},
+		{"ties sorted by name", "gitlub", codes, []string{"github", "gitlab"}},
+		{"exact match is left out", "x", codes, []string{}},
+		{"too far away", "youtube", codes, []string{}},
+		{"no candidates", "paul", nil, []string{}},
+		{"at most MAX_SUGGESTIONS", "paul", []string{"paula", "pauls", "saul", "pau", "raul"}, []string{"pau", "paula", "pauls"}},
+		{"much longer target", strings.Repeat("a", 10000), []string{"a", "aa"}, []string{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Suggest(test.target, test.candidates); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Suggest(%q) = %v, want %v", test.target, got, test.want)
+			}
+		})
+	}
+}
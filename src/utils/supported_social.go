@@ -1,110 +1,301 @@
 package utils
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
+	"sort"
 	"strings"
+	"sync"
 )
 
-// Social
+// Social is one platform of the registry
 type Social struct {
-	DisplayName string
-	Profile     string
+	DisplayName string `json:"displayName"`
+	// profile link without the scheme, <@> is replaced by the handle. Empty for
+	// the platforms only used for avatars. For the fediverse, the handle is
+	// user@instance: <@> is replaced by the user and <instance> by the instance.
+	Profile string `json:"profile"`
+	// unavatar.io can fetch the avatar of a handle, see SocialProvider for the
+	// socials with their own avatars
+	Unavatar bool `json:"unavatar"`
+	// the link is highlighted on the artist page
+	Special bool `json:"special"`
+	// other social codes for the same platform, the formatter rewrites them to
+	// the social code
+	Aliases []string `json:"aliases,omitempty"`
+	// like Aliases, but the parser warns about them
+	Deprecated []string `json:"deprecated,omitempty"`
 }
 
-// SupportedSocials
+// BUILTIN_SOCIALS is the registry used when SOCIALS_FILE isn't set, and the
+// base the file adds to
+var BUILTIN_SOCIALS = map[string]Social{
+	// supported by unavatar.io
+	"deviantart":    {"DeviantArt", "deviantart.com/<@>", true, false, nil, nil},
+	"dribbble":      {"Dribbble", "dribbble.com/<@>", true, false, nil, nil},
+	"duckduckgo":    {"DuckDuckGo", "", true, false, nil, nil},
+	"facebook":      {"Facebook", "fb.com/<@>", true, false, []string{"fb"}, nil},
+	"github":        {"GitHub", "github.com/<@>", true, false, nil, nil},
+	"google":        {"Google", "", true, false, nil, nil},
+	"gravatar":      {"Gravatar", "", true, false, nil, nil},
+	"instagram":     {"Instagram", "instagram.com/<@>", true, false, nil, nil},
+	"microlink":     {"Microlink", "", true, false, nil, nil},
+	"readcv":        {"ReadCV", "read.cv/<@>", true, false, nil, nil},
+	"reddit":        {"Reddit", "reddit.com/user/<@>", true, false, nil, nil},
+	"soundcloud":    {"SoundCloud", "soundcloud.com/<@>", true, false, nil, nil},
+	"subscribestar": {"SubscribeStar", "subscribestar.adult/<@>", true, false, nil, nil},
+	"substack":      {"Substack", "<@>.substack.com/", true, false, nil, nil},
+	"telegram":      {"Telegram", "t.me/<@>", true, false, nil, nil},
+	"x":             {"𝕏", "x.com/<@>", true, false, nil, []string{"twitter"}},
+	"youtube":       {"YouTube", "youtube.com/@<@>", true, false, nil, nil},
+
+	// links only
+	"artstation": {"ArtStation", "www.artstation.com/<@>", false, false, nil, nil},
+	"bluesky":    {"BlueSky", "bsky.app/profile/<@>", false, false, []string{"bsky"}, nil},
+	"boosty":     {"Boosty", "boosty.to/<@>", false, false, nil, nil},
+	"booth":      {"Booth.pm", "<@>.booth.pm", false, false, nil, nil},
+	"carrd.co":   {"Carrd.co", "<@>.carrd.co", false, true, nil, nil},
+	"fa":         {"FurAffinity 🐾", "www.furaffinity.net/user/<@>/", false, false, nil, nil},
+	"fanbox":     {"PixivFanbox", "<@>.fanbox.cc", false, false, nil, nil},
+	"gumroad":    {"Gumroad", "<@>.gumroad.com", false, false, nil, nil},
+	"itaku":      {"Itaku", "itaku.ee/profile/<@>", false, false, nil, nil},
+	"itch.io":    {"Itch.io", "itch.io/profile/<@>", false, false, nil, nil},
+	"kofi":       {"Ko-fi 🍵", "ko-fi.com/<@>", false, false, nil, nil},
+	"linktr.ee":  {"Linktr.ee 🌲", "linktr.ee/<@>", false, true, nil, nil},
+	"lit.link":   {"Lit.link", "lit.link/<@>", false, true, nil, nil},
+	"patreon":    {"Patreon", "www.patreon.com/<@>", false, false, nil, nil},
+	"picarto":    {"Picarto", "www.picarto.tv/<@>", false, false, nil, nil},
+	"pixiv":      {"Pixiv", "www.pixiv.net/en/users/<@>", false, false, nil, nil},
+	"plurk":      {"Plurk", "plurk.com/<@>", false, false, nil, nil},
+	"potofu.me":  {"Potofu.me", "potofu.me/<@>", false, true, nil, nil},
+	"skeb":       {"Skeb.jp", "skeb.jp/@<@>", false, false, nil, nil},
+	"threads":    {"Threads", "www.threads.net/@<@>", false, false, nil, nil},
+	"tumblr":     {"Tumblr", "<@>.tumblr.com", false, false, nil, nil},
+	"twitch":     {"Twitch", "www.twitch.tv/<@>", false, false, nil, nil},
+
+	// fediverse, avatars through microlink
+	"mastodon": {"Mastodon", "<instance>/@<@>", false, false, nil, nil},
+	"misskey":  {"Misskey", "<instance>/@<@>", false, false, nil, nil},
+	"pixelfed": {"Pixelfed", "<instance>/<@>", false, false, nil, nil},
+}
+
+// PROFILE_HOST_ALIASES are other hosts serving the profiles of the registry,
+// see MatchProfileURL
+var PROFILE_HOST_ALIASES = map[string]string{
+	"twitter.com":        "x.com",
+	"mobile.twitter.com": "x.com",
+	"facebook.com":       "fb.com",
+	"m.facebook.com":     "fb.com",
+	"m.youtube.com":      "youtube.com",
+}
+
+// SupportedSocials is the registry of platforms by social code. It can be
+// reloaded from SOCIALS_FILE while artists are being parsed, so every method
+// locks it.
 type SupportedSocials struct {
-	unavatar map[string]Social
-	extended map[string]Social
-	special  map[string]bool
-}
-
-func NewSocialDBInstance() SupportedSocials {
-	return SupportedSocials{
-		unavatar: map[string]Social{
-			"deviantart":    {"DeviantArt", "deviantart.com/<@>"},
-			"dribbble":      {"Dribbble", "dribbble.com/<@>"},
-			"duckduckgo":    {"DuckDuckGo", ""},
-			"facebook":      {"Facebook", "fb.com/<@>"},
-			"fb":            {"Facebook", "fb.com/<@>"},
-			"github":        {"GitHub", "github.com/<@>"},
-			"google":        {"Google", ""},
-			"gravatar":      {"Gravatar", ""},
-			"instagram":     {"Instagram", "instagram.com/<@>"},
-			"microlink":     {"Microlink", ""},
-			"readcv":        {"ReadCV", "read.cv/<@>"},
-			"reddit":        {"Reddit", "reddit.com/user/<@>"},
-			"soundcloud":    {"SoundCloud", "soundcloud.com/<@>"},
-			"subscribestar": {"SubscribeStar", "subscribestar.adult/<@>"},
-			"substack":      {"Substack", "<@>.substack.com/"},
-			"telegram":      {"Telegram", "t.me/<@>"},
-			"x":             {"𝕏", "x.com/<@>"},
-			"youtube":       {"YouTube", "youtube.com/@<@>"},
-		},
-		extended: map[string]Social{
-			"artstation": {"ArtStation", "www.artstation.com/<@>"},
-			"bluesky":    {"BlueSky", "bsky.app/profile/<@>"},
-			"boosty":     {"Boosty", "boosty.to/<@>"},
-			"booth":      {"Booth.pm", "<@>.booth.pm"},
-			"bsky":       {"BlueSky", "bsky.app/profile/<@>"},
-			"carrd.co":   {"Carrd.co", "<@>.carrd.co"},
-			"fa":         {"FurAffinity 🐾", "www.furaffinity.net/user/<@>/"},
-			"fanbox":     {"PixivFanbox", "<@>.fanbox.cc"},
-			"gumroad":    {"Gumroad", "<@>.gumroad.com"},
-			"itaku":      {"Itaku", "itaku.ee/profile/<@>"},
-			"itch.io":    {"Itch.io", "itch.io/profile/<@>"},
-			"kofi":       {"Ko-fi 🍵", "ko-fi.com/<@>"},
-			"linktr.ee":  {"Linktr.ee 🌲", "linktr.ee/<@>"},
-			"lit.link":   {"Lit.link", "lit.link/<@>"},
-			"patreon":    {"Patreon", "www.patreon.com/<@>"},
-			"picarto":    {"Picarto", "www.picarto.tv/<@>"},
-			"pixiv":      {"Pixiv", "www.pixiv.net/en/users/<@>"},
-			"plurk":      {"Plurk", "plurk.com/<@>"},
-			"potofu.me":  {"Potofu.me", "potofu.me/<@>"},
-			"skeb":       {"Skeb.jp", "skeb.jp/@<@>"},
-			"threads":    {"Threads", "www.threads.net/@<@>"},
-			"tumblr":     {"Tumblr", "<@>.tumblr.com"},
-			"twitch":     {"Twitch", "www.twitch.tv/<@>"},
-			"x":          {"𝕏", "twitter.com/<@>"},
-		},
-		special: map[string]bool{
-			"potofu.me": true,
-			"carrd.co":  true,
-			"linktr.ee": true,
-			"lit.link":  true,
-		},
-	}
-}
-
-func (ss *SupportedSocials) ToUnavatarLink(username, socialCode string) (string, error) {
-	if _, ok := ss.unavatar[socialCode]; ok {
-		return fmt.Sprintf("//unavatar.io/%s/%s", socialCode, username), nil
-	}
-	return "", fmt.Errorf("SupportedSocials.ToUnavatarLink: social code not found to create avatar link")
-}
-
-func (ss *SupportedSocials) ToProfileLink(username, socialCode string) (string, error) {
-	if _, ok := ss.unavatar[socialCode]; ok {
-		return strings.Replace(ss.unavatar[socialCode].Profile, "<@>", username, 1), nil
-	}
-	if _, ok := ss.extended[socialCode]; ok {
-		return strings.Replace(ss.extended[socialCode].Profile, "<@>", username, 1), nil
-	}
-	return "", fmt.Errorf("SupportedSocials.ToProfileLink: social code not found to create profile link")
+	mu sync.RWMutex
+	// by canonical social code
+	socials map[string]Social
+	// every social code and alias to its canonical code
+	codes map[string]string
+	// the deprecated aliases
+	deprecated map[string]bool
+	// built from socials
+	providers map[string]SocialProvider
 }
 
-func (ss *SupportedSocials) FormatDescription(socialCode, description string) (string, error) {
-	var socialName string
+func NewSocialDBInstance() *SupportedSocials {
+	ss := &SupportedSocials{}
+	if err := ss.set(BUILTIN_SOCIALS); err != nil {
+		panic(err)
+	}
+	return ss
+}
+
+// Load replaces the registry with the built-in socials plus the ones in the
+// JSON file at path, an object of Social by social code. A social in the file
+// replaces the built-in one with the same code. The registry is left untouched
+// if the file is invalid.
+func (ss *SupportedSocials) Load(path string) error {
+	rawBytes, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	fileSocials := make(map[string]Social)
+	if err := json.Unmarshal(rawBytes, &fileSocials); err != nil {
+		return fmt.Errorf("SupportedSocials.Load: %s: %w", path, err)
+	}
+
+	socials := make(map[string]Social, len(BUILTIN_SOCIALS)+len(fileSocials))
+	for code, social := range BUILTIN_SOCIALS {
+		socials[code] = social
+	}
+	for code, social := range fileSocials {
+		socials[code] = social
+	}
+	if err := ss.set(socials); err != nil {
+		return fmt.Errorf("SupportedSocials.Load: %s: %w", path, err)
+	}
+	return nil
+}
 
-	// find social display name
-	if social, ok := ss.unavatar[socialCode]; ok {
-		socialName = social.DisplayName
+// set checks the socials and swaps them in
+func (ss *SupportedSocials) set(socials map[string]Social) error {
+	codes := make(map[string]string, len(socials))
+	addCode := func(code, canonical string) error {
+		if code == "" || code != strings.ToLower(code) || strings.ContainsAny(code, "@, \t") {
+			return fmt.Errorf("invalid social code %q, must be lowercase without @, commas or spaces", code)
+		}
+		if other, ok := codes[code]; ok {
+			return fmt.Errorf("social code %q is defined by both %s and %s", code, other, canonical)
+		}
+		codes[code] = canonical
+		return nil
 	}
-	if socialName == "" {
-		if social, ok := ss.extended[socialCode]; ok {
-			socialName = social.DisplayName
+	for code, social := range socials {
+		if social.DisplayName == "" {
+			return fmt.Errorf("social %q has no display name", code)
+		}
+		if social.Profile != "" && !strings.Contains(social.Profile, "<@>") {
+			return fmt.Errorf("profile link of social %q has no <@> for the handle", code)
 		}
+		if err := addCode(code, code); err != nil {
+			return err
+		}
+	}
+	deprecated := make(map[string]bool)
+	for code, social := range socials {
+		for _, alias := range social.Aliases {
+			if err := addCode(alias, code); err != nil {
+				return err
+			}
+		}
+		for _, alias := range social.Deprecated {
+			if err := addCode(alias, code); err != nil {
+				return err
+			}
+			deprecated[alias] = true
+		}
+	}
+
+	providers := make(map[string]SocialProvider, len(socials))
+	for code, social := range socials {
+		providers[code] = newProvider(code, social)
 	}
 
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.socials = socials
+	ss.codes = codes
+	ss.deprecated = deprecated
+	ss.providers = providers
+	return nil
+}
+
+// get returns the canonical code and the social of a social code or alias
+func (ss *SupportedSocials) get(socialCode string) (string, Social, bool) {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+	canonical, ok := ss.codes[socialCode]
+	if !ok {
+		return "", Social{}, false
+	}
+	return canonical, ss.socials[canonical], true
+}
+
+// Canonical returns the social code of a social code or alias, and whether the
+// alias is deprecated
+func (ss *SupportedSocials) Canonical(socialCode string) (string, bool, bool) {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+	canonical, ok := ss.codes[socialCode]
+	return canonical, ss.deprecated[socialCode], ok
+}
+
+// Provider returns the provider of a social code or alias
+func (ss *SupportedSocials) Provider(socialCode string) (SocialProvider, bool) {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+	canonical, ok := ss.codes[socialCode]
+	if !ok {
+		return nil, false
+	}
+	return ss.providers[canonical], true
+}
+
+// MatchProfileURL finds the social whose profile link is link, which can have a
+// scheme, www., a query or a trailing slash. It returns the handle and the
+// canonical social code. Fediverse profiles aren't matched, any host could be
+// an instance.
+func (ss *SupportedSocials) MatchProfileURL(link string) (string, string, bool) {
+	link = trimProfileURL(link)
+
+	ss.mu.RLock()
+	codes := make([]string, 0, len(ss.socials))
+	for code := range ss.socials {
+		codes = append(codes, code)
+	}
+	socials, providers := ss.socials, ss.providers
+	ss.mu.RUnlock()
+	sort.Strings(codes)
+
+	for _, code := range codes {
+		profile := trimProfileURL(socials[code].Profile)
+		if profile == "" || strings.Contains(profile, "<instance>") {
+			continue
+		}
+		prefix, suffix, _ := strings.Cut(profile, "<@>")
+		if len(link) <= len(prefix)+len(suffix) ||
+			!strings.HasPrefix(link, prefix) || !strings.HasSuffix(link, suffix) {
+			continue
+		}
+
+		// the handle must give back the same link, so a path like
+		// instagram.com/p/xyz isn't taken for a profile
+		provider := providers[code]
+		handle := provider.NormalizeHandle(link[len(prefix) : len(link)-len(suffix)])
+		if provider.ValidateHandle(handle) != nil {
+			continue
+		}
+		if profileLink, err := provider.ProfileURL(handle); err != nil ||
+			!strings.EqualFold(trimProfileURL(profileLink), link) {
+			continue
+		}
+		return handle, code, true
+	}
+	return "", "", false
+}
+
+// trimProfileURL drops what doesn't change which profile a link is: the
+// scheme, www., the query, the fragment and trailing slashes. The host is
+// lowercased and replaced by the one of the registry, see PROFILE_HOST_ALIASES.
+func trimProfileURL(link string) string {
+	for _, scheme := range []string{"https:", "http:"} {
+		if len(link) >= len(scheme) && strings.EqualFold(link[:len(scheme)], scheme) {
+			link = link[len(scheme):]
+		}
+	}
+	link = strings.TrimPrefix(link, "//")
+	if i := strings.IndexAny(link, "?#"); i >= 0 {
+		link = link[:i]
+	}
+	link = strings.TrimRight(link, "/")
+
+	host, path, hasPath := strings.Cut(link, "/")
+	host = strings.TrimPrefix(strings.ToLower(host), "www.")
+	if alias, ok := PROFILE_HOST_ALIASES[host]; ok {
+		host = alias
+	}
+	if !hasPath {
+		return host
+	}
+	return host + "/" + path
+}
+
+func (ss *SupportedSocials) FormatDescription(socialCode, description string) (string, error) {
+	socialName := ss.DisplayName(socialCode)
+
 	switch {
 	case socialName == "" && description != "":
 		return description, nil
@@ -118,8 +309,42 @@ func (ss *SupportedSocials) FormatDescription(socialCode, description string) (s
 }
 
 func (ss *SupportedSocials) IsSpecial(socialCode string) bool {
-	if _, ok := ss.special[socialCode]; ok {
-		return true
+	_, social, _ := ss.get(socialCode)
+	return social.Special
+}
+
+// Codes returns every supported social code and alias but the deprecated ones,
+// sorted
+func (ss *SupportedSocials) Codes() []string {
+	return ss.codesWhere(func(SocialProvider) bool { return true })
+}
+
+// AvatarCodes returns the social codes and aliases that can be used for
+// avatars but the deprecated ones, sorted
+func (ss *SupportedSocials) AvatarCodes() []string {
+	return ss.codesWhere(func(provider SocialProvider) bool {
+		_, err := provider.AvatarURL("handle")
+		return err == nil
+	})
+}
+
+func (ss *SupportedSocials) codesWhere(keep func(SocialProvider) bool) []string {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+	codes := make([]string, 0, len(ss.codes))
+	for code, canonical := range ss.codes {
+		if !ss.deprecated[code] && keep(ss.providers[canonical]) {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// DisplayName returns the name of the social, empty if it's not supported
+func (ss *SupportedSocials) DisplayName(socialCode string) string {
+	if provider, ok := ss.Provider(socialCode); ok {
+		return provider.DisplayName()
 	}
-	return false
+	return ""
 }
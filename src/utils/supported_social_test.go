@@ -0,0 +1,201 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		socials map[string]Social
+		// part of the error, empty if the socials are valid
+		err string
+	}{
+		{
+			name:    "valid",
+			socials: map[string]Social{"x": {DisplayName: "𝕏", Profile: "x.com/<@>"}, "mail": {DisplayName: "Mail"}},
+		},
+		{
+			name:    "alias of another social",
+			socials: map[string]Social{"facebook": {DisplayName: "Facebook", Aliases: []string{"fb"}}, "fb": {DisplayName: "Fb"}},
+			err:     `social code "fb" is defined by both`,
+		},
+		{
+			name: "same alias twice",
+			socials: map[string]Social{
+				"bluesky": {DisplayName: "Bluesky", Aliases: []string{"sky"}},
+				"skeb":    {DisplayName: "Skeb", Aliases: []string{"sky"}},
+			},
+			err: `social code "sky" is defined by both`,
+		},
+		{
+			name: "deprecated alias of another social",
+			socials: map[string]Social{
+				"x":       {DisplayName: "𝕏", Deprecated: []string{"twitter"}},
+				"twitter": {DisplayName: "Twitter"},
+			},
+			err: `social code "twitter" is defined by both`,
+		},
+		{
+			name: "deprecated alias taken by an alias",
+			socials: map[string]Social{
+				"x":     {DisplayName: "𝕏", Deprecated: []string{"tw"}},
+				"tweet": {DisplayName: "Tweet", Aliases: []string{"tw"}},
+			},
+			err: `social code "tw" is defined by both`,
+		},
+		{
+			name:    "uppercase code",
+			socials: map[string]Social{"X": {DisplayName: "𝕏"}},
+			err:     "invalid social code",
+		},
+		{
+			name:    "alias with @",
+			socials: map[string]Social{"x": {DisplayName: "𝕏", Aliases: []string{"x@old"}}},
+			err:     "invalid social code",
+		},
+		{
+			name:    "no display name",
+			socials: map[string]Social{"x": {Profile: "x.com/<@>"}},
+			err:     "has no display name",
+		},
+		{
+			name:    "profile without handle",
+			socials: map[string]Social{"x": {DisplayName: "𝕏", Profile: "x.com/"}},
+			err:     "has no <@>",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ss := NewSocialDBInstance()
+			err := ss.set(test.socials)
+			switch {
+			case test.err == "" && err != nil:
+				t.Fatalf("set() = %v, want no error", err)
+			case test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)):
+				t.Fatalf("set() = %v, want an error containing %q", err, test.err)
+			}
+			// an invalid registry isn't swapped in
+			_, _, hasInstagram := ss.get("instagram")
+			if hasInstagram != (test.err != "") {
+				t.Errorf("registry replaced = %v, want %v", !hasInstagram, test.err == "")
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		err     bool
+		// social code to profile link of paul once loaded
+		profiles map[string]string
+	}{
+		{
+			name:     "adds to the built-in socials",
+			content:  `{"cara": {"displayName": "Cara", "profile": "cara.app/<@>", "aliases": ["caraapp"]}}`,
+			profiles: map[string]string{"cara": "cara.app/paul", "caraapp": "cara.app/paul", "x": "x.com/paul"},
+		},
+		{
+			name:     "replaces a built-in social",
+			content:  `{"x": {"displayName": "Twitter", "profile": "twitter.com/<@>", "unavatar": true}}`,
+			profiles: map[string]string{"x": "twitter.com/paul"},
+		},
+		{
+			name:     "invalid JSON",
+			content:  `{"cara": `,
+			err:      true,
+			profiles: map[string]string{"x": "x.com/paul"},
+		},
+		{
+			name:     "code taken by a built-in alias",
+			content:  `{"fb": {"displayName": "Fb", "profile": "fb.example/<@>"}}`,
+			err:      true,
+			profiles: map[string]string{"fb": "fb.com/paul"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "socials.json")
+			if err := os.WriteFile(path, []byte(test.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			ss := NewSocialDBInstance()
+			if err := ss.Load(path); (err != nil) != test.err {
+				t.Fatalf("Load() = %v, want error %v", err, test.err)
+			}
+			for code, want := range test.profiles {
+				provider, ok := ss.Provider(code)
+				if !ok {
+					t.Errorf("no provider for %s", code)
+					continue
+				}
+				if got, err := provider.ProfileURL("paul"); err != nil || got != want {
+					t.Errorf("%s ProfileURL(paul) = %q, %v, want %q", code, got, err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMatchProfileURL(t *testing.T) {
+	socials := NewSocialDBInstance()
+	tests := []struct {
+		link   string
+		handle string
+		code   string
+		ok     bool
+	}{
+		{"//instagram.com/paul", "paul", "instagram", true},
+		{"https://www.instagram.com/paul/", "paul", "instagram", true},
+		{"//instagram.com/paul?igsh=abc#top", "paul", "instagram", true},
+		{"//Instagram.COM/paul", "paul", "instagram", true},
+		{"//x.com/paul", "paul", "x", true},
+		{"http://twitter.com/paul", "paul", "x", true},
+		{"//github.com/paul", "paul", "github", true},
+		{"//bsky.app/profile/paul.bsky.social", "paul.bsky.social", "bluesky", true},
+		// not a profile
+		{"//instagram.com/p/xyz", "", "", false},
+		{"//instagram.com/", "", "", false},
+		{"//example.com/paul", "", "", false},
+		// any host could be an instance of the fediverse
+		{"//mastodon.art/@paul", "", "", false},
+	}
+	for _, test := range tests {
+		t.Run(test.link, func(t *testing.T) {
+			handle, code, ok := socials.MatchProfileURL(test.link)
+			if handle != test.handle || code != test.code || ok != test.ok {
+				t.Errorf("MatchProfileURL(%q) = %q, %q, %v, want %q, %q, %v",
+					test.link, handle, code, ok, test.handle, test.code, test.ok)
+			}
+		})
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	socials := NewSocialDBInstance()
+	tests := []struct {
+		code       string
+		canonical  string
+		deprecated bool
+		ok         bool
+	}{
+		{"x", "x", false, true},
+		{"twitter", "x", true, true},
+		{"fb", "facebook", false, true},
+		{"instagarm", "", false, false},
+	}
+	for _, test := range tests {
+		t.Run(test.code, func(t *testing.T) {
+			canonical, deprecated, ok := socials.Canonical(test.code)
+			if canonical != test.canonical || deprecated != test.deprecated || ok != test.ok {
+				t.Errorf("Canonical(%q) = %q, %v, %v, want %q, %v, %v",
+					test.code, canonical, deprecated, ok, test.canonical, test.deprecated, test.ok)
+			}
+		})
+	}
+}
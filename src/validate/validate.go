@@ -0,0 +1,93 @@
+package validate
+
+import (
+	"artistdb-go/src/artist"
+	"artistdb-go/src/migrations"
+	"artistdb-go/src/utils"
+	"context"
+	"database/sql"
+
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/sqlitedialect"
+	"github.com/uptrace/bun/driver/sqliteshim"
+)
+
+// Result is what a dry run of a reload found
+type Result struct {
+	// the artists would be loaded, i.e. there's no error
+	Valid       bool               `json:"valid"`
+	ArtistCount int                `json:"artistCount"`
+	AliasCount  int                `json:"aliasCount"`
+	Errors      artist.Diagnostics `json:"errors"`
+	Warnings    artist.Diagnostics `json:"warnings"`
+	// artists and socials left out in lenient mode
+	Skipped []artist.Skipped `json:"skipped"`
+	// compared with the live data, empty if the artists aren't valid
+	Changes artist.ChangeSet `json:"changes"`
+}
+
+// Sources parses the artists files and writes them to an in-memory database,
+// exactly like a reload would, then compares them with the live database. The
+// live database is only read, it can be nil to compare with no artists.
+func Sources(ctx context.Context, appState *utils.AppState, live bun.IDB, sources []artist.Source) (Result, error) {
+	artistsToDB, parsed := artist.ParseSources(appState, sources)
+	result := Result{
+		Valid:    !parsed.Diagnostics.HasErrors(),
+		Errors:   make(artist.Diagnostics, 0),
+		Warnings: make(artist.Diagnostics, 0),
+		Skipped:  parsed.Skipped,
+		Changes: artist.ChangeSet{
+			Added:    make([]string, 0),
+			Removed:  make([]string, 0),
+			Modified: make([]string, 0),
+		},
+	}
+	for _, diag := range parsed.Diagnostics {
+		switch diag.Severity {
+		case artist.SeverityError:
+			result.Errors = append(result.Errors, diag)
+		default:
+			result.Warnings = append(result.Warnings, diag)
+		}
+	}
+	if !result.Valid {
+		return result, nil
+	}
+	result.ArtistCount = parsed.ArtistCount
+	result.AliasCount = parsed.AliasCount
+
+	// catch what only the database rejects
+	db, err := newMemoryDB(ctx)
+	if err != nil {
+		return result, err
+	}
+	defer db.Close()
+	if _, err := artist.Sync(ctx, db, artistsToDB); err != nil {
+		return result, err
+	}
+
+	if live == nil {
+		for _, artistModel := range artistsToDB {
+			result.Changes.Added = append(result.Changes.Added, artistModel.ID)
+		}
+		return result, nil
+	}
+	result.Changes, err = artist.Diff(ctx, live, artistsToDB)
+	return result, err
+}
+
+// newMemoryDB opens an empty database with the current schema
+func newMemoryDB(ctx context.Context) (*bun.DB, error) {
+	sqldb, err := sql.Open(sqliteshim.ShimName, utils.SQLiteDSN("file::memory:"))
+	if err != nil {
+		return nil, err
+	}
+	// every connection to :memory: is a database of its own
+	sqldb.SetMaxOpenConns(1)
+	db := bun.NewDB(sqldb, sqlitedialect.New())
+	if err := migrations.Create(ctx, db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
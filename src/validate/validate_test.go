@@ -0,0 +1,106 @@
+package validate
+
+import (
+	"artistdb-go/src/artist"
+	"artistdb-go/src/utils"
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/uptrace/bun"
+)
+
+func TestSources(t *testing.T) {
+	ctx := context.Background()
+	live, err := newMemoryDB(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer live.Close()
+	liveArtists, _ := artist.ParseSources(utils.NewParseAppState(),
+		[]artist.Source{{Name: "live.txt", Content: "paul\npaul@x\n\nzed\nzed@x\n"}})
+	if _, err := artist.Sync(ctx, live, liveArtists); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		lenient bool
+		file    string
+		nilLive bool
+		valid   bool
+		errors  int
+		skipped int
+		artists int
+		aliases int
+		changes artist.ChangeSet
+	}{
+		{
+			name:    "changes against the live data",
+			file:    "amy,Amy,_,painter\namy@x\n\npaul,Paul\npaul@x\n",
+			valid:   true,
+			artists: 2,
+			aliases: 1,
+			changes: artist.ChangeSet{Added: []string{"amy"}, Removed: []string{"zed"}, Modified: []string{"paul"}},
+		},
+		{
+			name:    "no live data",
+			file:    "amy\namy@x\n",
+			nilLive: true,
+			valid:   true,
+			artists: 1,
+			changes: artist.ChangeSet{Added: []string{"amy"}, Removed: []string{}, Modified: []string{}},
+		},
+		{
+			name:    "errors give no changes",
+			file:    "paul\npaul@x\n\npaul\npaul@instagram\n",
+			errors:  1,
+			changes: artist.ChangeSet{Added: []string{}, Removed: []string{}, Modified: []string{}},
+		},
+		{
+			name:    "lenient mode skips the broken artist",
+			lenient: true,
+			file:    "paul\npaul@x\n\nzed,Zed\n",
+			valid:   true,
+			errors:  1,
+			skipped: 1,
+			artists: 1,
+			changes: artist.ChangeSet{Added: []string{}, Removed: []string{"zed"}, Modified: []string{}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.lenient {
+				t.Setenv("PARSE_MODE", "lenient")
+			}
+			var liveDB bun.IDB = live
+			if test.nilLive {
+				liveDB = nil
+			}
+			result, err := Sources(ctx, utils.NewParseAppState(), liveDB, []artist.Source{{Name: "new.txt", Content: test.file}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Valid != test.valid || len(result.Errors) != test.errors || len(result.Skipped) != test.skipped {
+				t.Errorf("Sources() = valid %v, %d errors, %d skipped, want %v, %d, %d: %+v",
+					result.Valid, len(result.Errors), len(result.Skipped), test.valid, test.errors, test.skipped, result)
+			}
+			if result.ArtistCount != test.artists || result.AliasCount != test.aliases {
+				t.Errorf("Sources() = %d artists, %d aliases, want %d, %d",
+					result.ArtistCount, result.AliasCount, test.artists, test.aliases)
+			}
+			if !reflect.DeepEqual(result.Changes, test.changes) {
+				t.Errorf("Sources() changes = %+v, want %+v", result.Changes, test.changes)
+			}
+		})
+	}
+
+	// the live data is only read
+	changes, err := artist.Diff(ctx, live, liveArtists)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changes.IsEmpty() {
+		t.Errorf("the live data changed: %+v", changes)
+	}
+}
@@ -0,0 +1,243 @@
+package watcher
+
+import (
+	"artistdb-go/src/utils"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rjeczalik/notify"
+)
+
+const (
+	MODE_NOTIFY = "notify"
+	MODE_POLL   = "poll"
+)
+
+// Watcher calls onChange when one of the artists files changes. It watches
+// the parent directory rather than the file itself, so editors that save by
+// writing a temp file and renaming it over the original are seen too, and
+// re-establishes the watch when the watched file or directory is replaced.
+// When the platform can't deliver file events, it falls back to polling the
+// files' mtime and hash.
+type Watcher struct {
+	inFile       string
+	mode         string
+	pollInterval time.Duration
+	onChange     func(reason string)
+}
+
+func NewWatcher(inFile, mode string, pollInterval time.Duration, onChange func(reason string)) *Watcher {
+	return &Watcher{
+		inFile:       inFile,
+		mode:         mode,
+		pollInterval: pollInterval,
+		onChange:     onChange,
+	}
+}
+
+// Run watches until ctx is done
+func (w *Watcher) Run(ctx context.Context) {
+	if w.mode != MODE_POLL {
+		err := w.runNotify(ctx)
+		if err == nil {
+			return
+		}
+		slog.Warn("can't watch for file events, falling back to polling", "err", err, "interval", w.pollInterval)
+	}
+	w.runPoll(ctx)
+}
+
+// target is what runNotify watches and how it filters the events
+type target struct {
+	watchPath string
+	// the watched directory, the watch is established again if it's replaced
+	dirPath string
+	dirStat os.FileInfo
+	// IN_FILE itself, replacing it (e.g. swapping a symlink) triggers a reload
+	fileStat os.FileInfo
+	accept   func(path string) bool
+}
+
+func (w *Watcher) resolveTarget() (target, error) {
+	absPath, err := filepath.Abs(w.inFile)
+	if err != nil {
+		return target{}, err
+	}
+	stat, err := os.Stat(absPath)
+	if err != nil {
+		return target{}, err
+	}
+
+	// every artists file in the directory and its subdirectories, skipping the
+	// hidden ones like ListInFiles does
+	if stat.IsDir() {
+		root := absPath
+		if resolved, err := filepath.EvalSymlinks(absPath); err == nil {
+			root = resolved
+		}
+		return target{
+			watchPath: filepath.Join(absPath, "..."),
+			dirPath:   absPath,
+			dirStat:   stat,
+			fileStat:  stat,
+			accept: func(path string) bool {
+				return utils.IsInFileUnder(root, path) || utils.IsInFileUnder(absPath, path)
+			},
+		}, nil
+	}
+
+	// only the artists file in its parent directory, notify reports the paths
+	// with symlinks resolved
+	parentDir, err := filepath.EvalSymlinks(filepath.Dir(absPath))
+	if err != nil {
+		return target{}, err
+	}
+	dirStat, err := os.Stat(parentDir)
+	if err != nil {
+		return target{}, err
+	}
+	baseName := filepath.Base(absPath)
+	return target{
+		watchPath: parentDir,
+		dirPath:   parentDir,
+		dirStat:   dirStat,
+		fileStat:  stat,
+		accept: func(path string) bool {
+			return filepath.Base(path) == baseName && filepath.Dir(path) == parentDir
+		},
+	}, nil
+}
+
+func (w *Watcher) runNotify(ctx context.Context) error {
+	for {
+		target, err := w.resolveTarget()
+		if err != nil {
+			return err
+		}
+		eventInfoCh := make(chan notify.EventInfo, 16)
+		if err := notify.Watch(target.watchPath, eventInfoCh,
+			notify.InCloseWrite,
+			notify.InCreate,
+			notify.InMovedTo,
+			notify.InMovedFrom,
+			notify.InDelete,
+		); err != nil {
+			return err
+		}
+		slog.Info("watching for changes", "path", target.watchPath)
+
+		rewatch := w.watchEvents(ctx, eventInfoCh, target)
+		notify.Stop(eventInfoCh)
+		if !rewatch {
+			return nil
+		}
+	}
+}
+
+// watchEvents forwards the accepted events until ctx is done, or until the
+// watched directory is replaced, in which case it returns true
+func (w *Watcher) watchEvents(ctx context.Context, eventInfoCh chan notify.EventInfo, target target) bool {
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case event := <-eventInfoCh:
+			if target.accept(event.Path()) {
+				// a rename-save replaces IN_FILE too, the ticker mustn't report
+				// it a second time
+				if fileStat, err := os.Stat(w.inFile); err == nil {
+					target.fileStat = fileStat
+				}
+				w.onChange(fmt.Sprintf("%s %s", event.Event(), event.Path()))
+			}
+		case <-ticker.C:
+			if dirStat, err := os.Stat(target.dirPath); err == nil && !os.SameFile(dirStat, target.dirStat) {
+				slog.Info("watched directory replaced, watching again", "path", target.dirPath)
+				w.onChange("replaced " + target.dirPath)
+				return true
+			}
+			if fileStat, err := os.Stat(w.inFile); err == nil && !os.SameFile(fileStat, target.fileStat) {
+				target.fileStat = fileStat
+				w.onChange("replaced " + w.inFile)
+			}
+		}
+	}
+}
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+	hash    string
+}
+
+func (w *Watcher) runPoll(ctx context.Context) {
+	slog.Info("polling for changes", "path", w.inFile, "interval", w.pollInterval)
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+	states := w.snapshot(nil)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		newStates := w.snapshot(states)
+		if changed := changedFile(states, newStates); changed != "" {
+			w.onChange("polled change " + changed)
+		}
+		states = newStates
+	}
+}
+
+// snapshot stats every artists file, only hashing the ones whose mtime or size
+// differ from prev
+func (w *Watcher) snapshot(prev map[string]fileState) map[string]fileState {
+	states := make(map[string]fileState)
+	inFiles, err := utils.ListInFiles(w.inFile)
+	if err != nil {
+		return states
+	}
+	for _, path := range inFiles {
+		stat, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		state := fileState{modTime: stat.ModTime(), size: stat.Size()}
+		if prevState, ok := prev[path]; ok && prevState.modTime.Equal(state.modTime) && prevState.size == state.size {
+			state.hash = prevState.hash
+		} else {
+			rawBytes, err := os.ReadFile(path)
+			if err != nil {
+				continue
+			}
+			hash := sha256.Sum256(rawBytes)
+			state.hash = hex.EncodeToString(hash[:])
+		}
+		states[path] = state
+	}
+	return states
+}
+
+// changedFile returns a file that was added, removed or whose content changed
+// between the two snapshots, empty if none
+func changedFile(prev, current map[string]fileState) string {
+	for path, state := range current {
+		if prevState, ok := prev[path]; !ok || prevState.hash != state.hash {
+			return path
+		}
+	}
+	for path := range prev {
+		if _, ok := current[path]; !ok {
+			return path
+		}
+	}
+	return ""
+}
@@ -0,0 +1,68 @@
+package watcher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rjeczalik/notify"
+)
+
+// event is a notify.EventInfo sent by the test instead of the platform
+type event struct {
+	path string
+}
+
+func (e event) Event() notify.Event { return notify.InMovedTo }
+func (e event) Path() string        { return e.path }
+func (e event) Sys() interface{}    { return nil }
+
+func TestWatchEventsRenameSave(t *testing.T) {
+	dir := t.TempDir()
+	inFile := filepath.Join(dir, "artists.txt")
+	if err := os.WriteFile(inFile, []byte("paul\npaul@x\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	reasons := make([]string, 0)
+	w := NewWatcher(inFile, MODE_NOTIFY, 10*time.Millisecond, func(reason string) {
+		mu.Lock()
+		defer mu.Unlock()
+		reasons = append(reasons, reason)
+	})
+	target, err := w.resolveTarget()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// save like an editor does, the file gets a new inode
+	tmpFile := filepath.Join(dir, ".artists.txt.swp")
+	if err := os.WriteFile(tmpFile, []byte("paul\npaul@instagram\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmpFile, inFile); err != nil {
+		t.Fatal(err)
+	}
+	// the event is waiting before the first tick of the inode check
+	eventInfoCh := make(chan notify.EventInfo, 1)
+	eventInfoCh <- event{path: filepath.Join(target.dirPath, "artists.txt")}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w.watchEvents(ctx, eventInfoCh, target)
+	}()
+
+	// several ticks of the inode check
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	<-done
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reasons) != 1 {
+		t.Errorf("onChange called with %q, want once for the event", reasons)
+	}
+}